@@ -10,9 +10,10 @@ import (
 
 	"cache-service/internal/cache"
 	"cache-service/internal/config"
-	"cache-service/internal/evictors"
 	"cache-service/internal/server"
 	"cache-service/internal/telemetry"
+
+	"go.opentelemetry.io/otel"
 )
 
 func main() {
@@ -31,12 +32,26 @@ func main() {
 	cacheCtx, cacheCancel := context.WithCancel(context.Background())
 	defer cacheCancel()
 
-	meterProvider, shutdownMeterProvider, err := telemetry.NewMeterProvider(cacheCtx)
+	res, err := telemetry.NewResource(cacheCtx)
+	if err != nil {
+		slog.Error("failed to create telemetry resource:", "err", err)
+		os.Exit(1)
+	}
+
+	meterProvider, metricsHandler, shutdownMeterProvider, err := telemetry.NewMeterProvider(cacheCtx, cfg.MetricsExporter, res)
 	if err != nil {
 		slog.Error("failed to create meter provider:", "err", err)
-	} else {
-		defer shutdownMeterProvider()
+		os.Exit(1)
 	}
+	defer shutdownMeterProvider()
+
+	tracerProvider, shutdownTracerProvider, err := telemetry.NewTracerProvider(cacheCtx, cfg.TracesExporter, res)
+	if err != nil {
+		slog.Error("failed to create tracer provider:", "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracerProvider()
+	otel.SetTracerProvider(tracerProvider)
 
 	// Get a meter for the cache
 	meter := meterProvider.Meter("cache-service/cache")
@@ -45,15 +60,19 @@ func main() {
 		slog.Error("failed to create cache metrics:", "err", err)
 	}
 
-	// Create a cache, this also creates the shards of the cache
+	// Create a cache, this also creates the shards of the cache.
+	// Stale values are not served, as nothing over HTTP registers a loader to refresh them.
 	cache, err := cache.NewCache(
 		cacheCtx,
 		cache.WithMaxSize(cfg.MaxCacheSize),
 		cache.WithMaxKeys(cfg.MaxKeys),
+		cache.WithSizeAccounting(cfg.SizeAccounting),
 		cache.WithTTL(cfg.CacheTTL),
+		cache.WithExpirySweepInterval(cfg.ExpirySweepInterval),
 		cache.WithShardCount(512),
 		cache.WithMetrics(cacheMetrics),
-		cache.WithEvictorFactory(func() evictors.Evictor { return evictors.NewLRUEvictor() }))
+		cache.WithTracerProvider(tracerProvider),
+		cache.WithEvictorFactory(cfg.EvictorFactory))
 
 	if err != nil {
 		slog.Error("failed to create cache:", "err", err)
@@ -61,7 +80,12 @@ func main() {
 	}
 
 	// Create a cache server for communicating with the ourside world
-	cacheServer, err := server.NewCacheServer(cfg.Port, cache)
+	serverOpts := []server.ServerOption{server.WithTracerProvider(tracerProvider)}
+	if metricsHandler != nil {
+		serverOpts = append(serverOpts, server.WithMetricsHandler(metricsHandler))
+	}
+
+	cacheServer, err := server.NewCacheServer(cfg.Port, cache, serverOpts...)
 	if err != nil {
 		slog.Error("failed to create cache server:", "err", err)
 		os.Exit(1)
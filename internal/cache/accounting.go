@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"cache-service/internal/evictors"
+)
+
+// SizeAccounting decides what an entry is charged against the cache's max size
+type SizeAccounting int
+
+const (
+	// AccountPayload charges the length of the value, max size bounds the bytes of the values stored
+	AccountPayload SizeAccounting = iota
+
+	// AccountHeap charges the estimated heap of the entry: the key, the value and the per entry overhead
+	// of the shard and its evictor, so max size bounds the memory the cache actually uses
+	AccountHeap
+)
+
+// Heap used per entry by a shard, not counting the key and the value. Measured with runtime.MemStats,
+// see TestHeapAccountingMatchesMemStats.
+const (
+	// shardEntryOverhead covers the map entry, the cacheItem and the slot in the expiry heap.
+	// Measure it again whenever cacheItem changes, the test fails once it is more than 15% off.
+	shardEntryOverhead = 188
+
+	// defaultEvictorEntryOverhead is charged for evictors that do not implement evictors.EntrySizer,
+	// it matches an evictor that keeps a map and a linked list like the LRU one
+	defaultEvictorEntryOverhead = 105
+)
+
+func (a SizeAccounting) String() string {
+	switch a {
+	case AccountPayload:
+		return "payload"
+	case AccountHeap:
+		return "heap"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSizeAccounting parses the name of a SizeAccounting, either payload or heap
+func ParseSizeAccounting(name string) (SizeAccounting, error) {
+	switch strings.ToLower(name) {
+	case "payload":
+		return AccountPayload, nil
+	case "heap":
+		return AccountHeap, nil
+	default:
+		return 0, fmt.Errorf("unknown size accounting %q, expected payload or heap", name)
+	}
+}
+
+// entryOverhead returns the heap a shard using the evictor spends on every entry besides its key and value
+func entryOverhead(evictor evictors.Evictor) int64 {
+	if sizer, ok := evictor.(evictors.EntrySizer); ok {
+		return shardEntryOverhead + sizer.EntryOverhead()
+	}
+	return shardEntryOverhead + defaultEvictorEntryOverhead
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+
+	"cache-service/internal/evictors"
+)
+
+// TestHeapAccountingMatchesMemStats fills a cache with every eviction policy and compares
+// the estimated heap with the growth of the heap reported by the runtime.
+// The per entry overheads in accounting.go and the evictors package were calibrated with it.
+// It measures the heap of the whole process, so it is skipped in short mode:
+//
+//	go test -run HeapAccountingMatchesMemStats -v ./internal/cache
+func TestHeapAccountingMatchesMemStats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("fills a cache with every eviction policy")
+	}
+
+	// The shards hold as many keys each as with the default shard count and 200k keys,
+	// map buckets are amortized differently in shards that hold far fewer keys
+	const (
+		entries = 50_000
+		shards  = DefaultShardCount / 4
+	)
+
+	for _, name := range evictors.Names() {
+		t.Run(name, func(t *testing.T) {
+			factory, _ := evictors.Lookup(name)
+			metrics := createTestMetrics(t)
+
+			// Stops the sweeper, so the cache is released before the next policy is measured
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			before := heapInUse()
+			cacheInstance, _ := NewCache(ctx,
+				WithShardCount(shards),
+				WithMaxKeys(entries),
+				WithMaxSize(math.MaxInt64),
+				WithSizeAccounting(AccountHeap),
+				WithEvictorFactory(factory),
+				WithMetrics(metrics))
+
+			for i := range entries {
+				cacheInstance.Set(fmt.Sprintf("key-%08d", i), make([]byte, 100))
+			}
+
+			estimated := cacheInstance.Stats().Bytes
+			measured := int64(heapInUse() - before)
+			runtime.KeepAlive(cacheInstance)
+
+			ratio := float64(estimated) / float64(measured)
+			t.Logf("estimated %d bytes, measured %d bytes, ratio %.3f", estimated, measured, ratio)
+			if ratio < 0.85 || ratio > 1.15 {
+				t.Fatalf("expected the estimate to be within 15%% of the heap, estimated %d measured %d", estimated, measured)
+			}
+		})
+	}
+}
+
+// heapInUse returns the bytes of live heap objects after a full collection
+func heapInUse() uint64 {
+	runtime.GC()
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.HeapAlloc
+}
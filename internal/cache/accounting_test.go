@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cache-service/internal/evictors"
+)
+
+func TestParseSizeAccounting(t *testing.T) {
+	for _, accounting := range []SizeAccounting{AccountPayload, AccountHeap} {
+		parsed, err := ParseSizeAccounting(accounting.String())
+		if err != nil || parsed != accounting {
+			t.Fatalf("expected %s to parse back, got %s, %v", accounting, parsed, err)
+		}
+	}
+
+	if _, err := ParseSizeAccounting("rss"); err == nil {
+		t.Fatalf("expected an error for an unknown size accounting")
+	}
+}
+
+func TestHeapAccountingChargesKeyAndOverhead(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithSizeAccounting(AccountHeap), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.Set("key", make([]byte, 10))
+
+	expected := int64(len("key")+10) + shardEntryOverhead + lruEntryOverheadForTest(t)
+	if bytes := cacheInstance.Stats().Bytes; bytes != expected {
+		t.Fatalf("expected the entry to be charged %d bytes, got %d", expected, bytes)
+	}
+}
+
+func TestHeapAccountingBoundsSmallEntries(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(10_000), WithSizeAccounting(AccountHeap), WithMetrics(createTestMetrics(t)))
+
+	for i := range 1000 {
+		cacheInstance.Set(fmt.Sprintf("key-%d", i), []byte{1})
+	}
+
+	// With payload accounting all 1000 one byte values would fit
+	stats := cacheInstance.Stats()
+	if stats.Keys >= 1000/10 || stats.Bytes > 10_000 {
+		t.Fatalf("expected the overhead to limit the cache to a few dozen keys, got %d keys and %d bytes", stats.Keys, stats.Bytes)
+	}
+}
+
+func lruEntryOverheadForTest(tb testing.TB) int64 {
+	sizer, ok := evictors.Evictor(evictors.NewLRUEvictor()).(evictors.EntrySizer)
+	if !ok {
+		tb.Fatalf("expected the LRU evictor to report its entry overhead")
+	}
+	return sizer.EntryOverhead()
+}
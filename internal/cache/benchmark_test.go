@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync/atomic"
 	"testing"
+
+	"cache-service/internal/evictors"
 )
 
 func BenchmarkCacheReadWrite(b *testing.B) {
@@ -75,3 +77,148 @@ func BenchmarkCacheParallelWrites(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkCacheSetUnderMemoryPressure fills a single shard to its size limit
+// so that every Set of a new key has to make space before it can be stored.
+func BenchmarkCacheSetUnderMemoryPressure(b *testing.B) {
+	for _, keys := range []int{10_000, 100_000} {
+		b.Run(fmt.Sprintf("keys=%d", keys), func(b *testing.B) {
+			metrics := createTestMetrics(b)
+			value := []byte("0123456789")
+			cacheInstance, _ := NewCache(
+				context.Background(),
+				WithShardCount(1),
+				WithMaxKeys(keys*2),
+				WithMaxSize(int64(keys*len(value))),
+				WithMetrics(metrics))
+
+			for i := range keys {
+				cacheInstance.Set(fmt.Sprintf("fill%d", i), value)
+			}
+
+			b.ResetTimer()
+
+			for i := 0; b.Loop(); i++ {
+				cacheInstance.Set(fmt.Sprintf("k%d", i), value)
+			}
+		})
+	}
+}
+
+// BenchmarkCacheParallelReadsManyKeys spreads hits over many keys in few shards,
+// so concurrent readers mostly contend on the shard lock rather than on a single key.
+func BenchmarkCacheParallelReadsManyKeys(b *testing.B) {
+	metrics := createTestMetrics(b)
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(4), WithMetrics(metrics))
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+		cacheInstance.Set(keys[i], []byte("v"))
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cacheInstance.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkShardParallelReads(b *testing.B) {
+	metrics := createTestMetrics(b)
+	shard, _ := newShard(context.Background(), "s1", DefaultTTL, DefaultMaxSize, DefaultMaxKeys, newLRUEvictorForTest(), metrics)
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+		shard.set(keys[i], []byte("v"))
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			shard.get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkCacheParallelReadsByEvictor(b *testing.B) {
+	factories := []struct {
+		name    string
+		factory func() evictors.Evictor
+	}{
+		{"lru", func() evictors.Evictor { return evictors.NewLRUEvictor() }},
+		{"sieve", func() evictors.Evictor { return evictors.NewSieveEvictor() }},
+		{"clock", func() evictors.Evictor { return evictors.NewClockEvictor() }},
+	}
+
+	for _, f := range factories {
+		b.Run(f.name, func(b *testing.B) {
+			metrics := createTestMetrics(b)
+			cacheInstance, _ := NewCache(context.Background(), WithShardCount(4), WithEvictorFactory(f.factory), WithMetrics(metrics))
+
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("k%d", i)
+				cacheInstance.Set(keys[i], []byte("v"))
+			}
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					cacheInstance.Get(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkShardLookup(b *testing.B) {
+	metrics := createTestMetrics(b)
+	shardManager, _ := newShardManager(context.Background(), DefaultShardCount, DefaultTTL, 0, 1024, 10, AccountPayload, newLRUEvictorForTest, metrics, nil)
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%d", i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; b.Loop(); i++ {
+		shardManager.GetShard(keys[i%len(keys)])
+	}
+}
+
+// BenchmarkShardDistribution reports how many more keys the busiest shard holds than the average one
+func BenchmarkShardDistribution(b *testing.B) {
+	const keyCount = 1_000_000
+
+	for _, shardCount := range []int{8, 256, 512} {
+		b.Run(fmt.Sprintf("shards=%d", shardCount), func(b *testing.B) {
+			var skew float64
+			for b.Loop() {
+				counts := make([]int, shardCount)
+				for i := range keyCount {
+					counts[shardIndex(fmt.Sprintf("user:%d", i), shardCount)]++
+				}
+
+				highest := 0
+				for _, count := range counts {
+					highest = max(highest, count)
+				}
+				skew = float64(highest) / (float64(keyCount) / float64(shardCount))
+			}
+			b.ReportMetric(skew, "max/mean")
+		})
+	}
+}
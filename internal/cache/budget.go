@@ -0,0 +1,56 @@
+package cache
+
+import "sync/atomic"
+
+// budget is the capacity shared by all shards of a cache.
+// A shard reserves bytes and keys from it before storing an item and releases them when the item is removed,
+// so one shard can grow past its fair share while the others have room to spare.
+// It is updated with atomics, shards never need each other's locks to account for space.
+type budget struct {
+	maxBytes int64
+	maxKeys  int64
+	bytes    atomic.Int64
+	keys     atomic.Int64
+}
+
+func newBudget(maxBytes int64, maxKeys int) *budget {
+	return &budget{maxBytes: maxBytes, maxKeys: int64(maxKeys)}
+}
+
+// reserveBytes takes n bytes from the budget, it fails if that would exceed maxBytes.
+// A negative n, a value replaced by a smaller one, always succeeds.
+func (b *budget) reserveBytes(n int64) bool {
+	return reserve(&b.bytes, n, b.maxBytes)
+}
+
+// reserveKey takes a key from the budget, it fails if all keys are in use
+func (b *budget) reserveKey() bool {
+	return reserve(&b.keys, 1, b.maxKeys)
+}
+
+func (b *budget) release(bytes int64, keys int64) {
+	b.bytes.Add(-bytes)
+	b.keys.Add(-keys)
+}
+
+// hasRoomFor reports whether n more bytes fit at the moment, without reserving them
+func (b *budget) hasRoomFor(n int64) bool {
+	return b.bytes.Load()+n <= b.maxBytes
+}
+
+// overBy returns how many bytes have to be freed before n more bytes fit
+func (b *budget) overBy(n int64) int64 {
+	return b.bytes.Load() + n - b.maxBytes
+}
+
+func reserve(counter *atomic.Int64, n int64, limit int64) bool {
+	for {
+		current := counter.Load()
+		if n > 0 && current+n > limit {
+			return false
+		}
+		if counter.CompareAndSwap(current, current+n) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"cache-service/internal/evictors"
+)
+
+func TestBudgetReserve(t *testing.T) {
+	b := newBudget(10, 2)
+
+	if !b.reserveBytes(6) || !b.reserveBytes(4) {
+		t.Fatalf("expected 10 bytes to fit")
+	}
+	if b.reserveBytes(1) {
+		t.Fatalf("expected the budget to be full")
+	}
+	if !b.reserveBytes(-3) || !b.hasRoomFor(3) || b.hasRoomFor(4) {
+		t.Fatalf("expected a shrinking value to give back 3 bytes")
+	}
+	if overBy := b.overBy(5); overBy != 2 {
+		t.Fatalf("expected to be 2 bytes short, got %d", overBy)
+	}
+
+	if !b.reserveKey() || !b.reserveKey() || b.reserveKey() {
+		t.Fatalf("expected exactly 2 keys to fit")
+	}
+	b.release(7, 1)
+	if b.bytes.Load() != 0 || !b.reserveKey() {
+		t.Fatalf("expected released bytes and keys to be available again")
+	}
+}
+
+// keysOnShard returns n keys that the cache places on the shard at index
+func keysOnShard(cacheInstance *Cache, index int, n int) []string {
+	keys := make([]string, 0, n)
+	for i := 0; len(keys) < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if shardIndex(key, len(cacheInstance.shardManager.shards)) == index {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestCacheValueLargerThanFairShare(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(4), WithMaxSize(100), WithMetrics(createTestMetrics(t)))
+
+	// Each shard's fair share is 25 bytes, the value only has to fit in the whole cache
+	if err := cacheInstance.Set("large", make([]byte, 60)); err != nil {
+		t.Fatalf("expected a value within the cache's capacity to be stored, got %v", err)
+	}
+	if err := cacheInstance.Set("too-large", make([]byte, 101)); err != ErrValueTooLarge {
+		t.Fatalf("expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+func TestCacheSkewedKeysUseWholeBudget(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(4), WithMaxSize(100), WithMaxKeys(8), WithMetrics(createTestMetrics(t)))
+
+	// All keys land on one shard, far beyond its fair share of 2 keys and 25 bytes
+	keys := keysOnShard(cacheInstance, 0, 9)
+	for _, key := range keys[:8] {
+		if err := cacheInstance.Set(key, make([]byte, 10)); err != nil {
+			t.Fatalf("expected %s to be stored while the cache has room, got %v", key, err)
+		}
+	}
+
+	if err := cacheInstance.Set(keys[8], make([]byte, 1)); err != ErrTooManyKeys {
+		t.Fatalf("expected ErrTooManyKeys once the cache holds maxKeys keys, got %v", err)
+	}
+}
+
+func TestCacheReclaimsFromLargestShard(t *testing.T) {
+	var evictedKeys []string
+	onEvict := func(key string, _ []byte, reason EvictionReason) {
+		if reason == EvictedCapacity {
+			evictedKeys = append(evictedKeys, key)
+		}
+	}
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(2), WithMaxSize(40), WithOnEvict(onEvict), WithMetrics(createTestMetrics(t)))
+
+	large := keysOnShard(cacheInstance, 0, 4)
+	for _, key := range large {
+		cacheInstance.Set(key, make([]byte, 10))
+	}
+
+	// Shard 1 is empty and within its fair share, so the space has to come from shard 0
+	small := keysOnShard(cacheInstance, 1, 1)[0]
+	if err := cacheInstance.Set(small, make([]byte, 10)); err != nil {
+		t.Fatalf("expected the write to reclaim space from the other shard, got %v", err)
+	}
+	if len(evictedKeys) == 0 || shardIndex(evictedKeys[0], 2) != 0 {
+		t.Fatalf("expected a key of shard 0 to be evicted, got %v", evictedKeys)
+	}
+
+	stats := cacheInstance.Stats()
+	if stats.Bytes > 40 || cacheInstance.shardManager.budget.bytes.Load() != stats.Bytes {
+		t.Fatalf("expected the budget to match the %d bytes stored", stats.Bytes)
+	}
+}
+
+func TestCacheBudgetMatchesShardsUnderConcurrentWrites(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(8), WithMaxSize(1000), WithMaxKeys(200), WithMetrics(createTestMetrics(t)))
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 2000 {
+				key := fmt.Sprintf("key-%d", (worker*7919+i)%500)
+				switch i % 5 {
+				case 0:
+					cacheInstance.Delete(key)
+				default:
+					cacheInstance.Set(key, make([]byte, 1+i%20))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cacheInstance.Stats()
+	budget := cacheInstance.shardManager.budget
+	if budget.bytes.Load() != stats.Bytes || budget.keys.Load() != int64(stats.Keys) {
+		t.Fatalf("budget holds %d bytes and %d keys, shards hold %d bytes and %d keys",
+			budget.bytes.Load(), budget.keys.Load(), stats.Bytes, stats.Keys)
+	}
+	if stats.Bytes > 1000 || stats.Keys > 200 {
+		t.Fatalf("expected the cache to stay within its capacity, got %d bytes and %d keys", stats.Bytes, stats.Keys)
+	}
+}
+
+func TestCacheBudgetNeverExceedsMaxWhenUpdatedKeyIsEvicted(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(2), WithMaxSize(100), WithMaxKeys(10), WithMetrics(createTestMetrics(t)))
+	budget := cacheInstance.shardManager.budget
+
+	// The keys of shard 0 are written round robin, so the key being updated is always the least recently used one
+	// and growing it evicts the key itself, while shard 1 keeps taking whatever the budget has left
+	writers := []struct {
+		keys  []string
+		sizes []int
+	}{
+		// Shard 0 grows past its fair share of 50 bytes, so it makes space for its own updates
+		{keysOnShard(cacheInstance, 0, 4), []int{10, 10, 10, 10, 30, 30, 30, 30}},
+		{keysOnShard(cacheInstance, 1, 50), []int{1, 5}},
+	}
+	var wg sync.WaitGroup
+	for _, writer := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 20000 {
+				cacheInstance.Set(writer.keys[i%len(writer.keys)], make([]byte, writer.sizes[i%len(writer.sizes)]))
+
+				if bytes, keys := budget.bytes.Load(), budget.keys.Load(); bytes > budget.maxBytes || keys > budget.maxKeys {
+					t.Errorf("expected the budget to stay within %d bytes and %d keys, got %d bytes and %d keys",
+						budget.maxBytes, budget.maxKeys, bytes, keys)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cacheInstance.Stats()
+	if budget.bytes.Load() != stats.Bytes || budget.keys.Load() != int64(stats.Keys) {
+		t.Fatalf("budget holds %d bytes and %d keys, shards hold %d bytes and %d keys",
+			budget.bytes.Load(), budget.keys.Load(), stats.Bytes, stats.Keys)
+	}
+}
+
+// budgetTakingEvictor takes bytes from the budget the first time it evicts, like a write to another shard would
+type budgetTakingEvictor struct {
+	*evictors.LRUEvictor
+	budget *budget
+	take   int64
+}
+
+func (e *budgetTakingEvictor) Evict(count int) []string {
+	if e.take > 0 {
+		e.budget.reserveBytes(e.take)
+		e.take = 0
+	}
+	return e.LRUEvictor.Evict(count)
+}
+
+func TestCacheReservesAgainWhenUpdatedKeyIsEvicted(t *testing.T) {
+	evictor := &budgetTakingEvictor{LRUEvictor: evictors.NewLRUEvictor()}
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(40), WithMaxKeys(10),
+		WithEvictorFactory(func() evictors.Evictor { return evictor }), WithMetrics(createTestMetrics(t)))
+	budget := cacheInstance.shardManager.budget
+	evictor.budget = budget
+
+	cacheInstance.Set("a", make([]byte, 10))
+	cacheInstance.Set("b", make([]byte, 10))
+	cacheInstance.Set("c", make([]byte, 15))
+
+	// Growing a evicts a itself, and the 5 bytes left in the budget are taken while it is evicted,
+	// so the 10 bytes a gave back have to be reserved again rather than added back
+	evictor.take = 5
+	if err := cacheInstance.Set("a", make([]byte, 20)); err != nil {
+		t.Fatalf(setErrStr, err)
+	}
+	if bytes := budget.bytes.Load(); bytes > budget.maxBytes {
+		t.Fatalf("expected the budget to stay within %d bytes, got %d", budget.maxBytes, bytes)
+	}
+
+	budget.release(5, 0)
+	if stats := cacheInstance.Stats(); budget.bytes.Load() != stats.Bytes || budget.keys.Load() != int64(stats.Keys) {
+		t.Fatalf("budget holds %d bytes and %d keys, shards hold %d bytes and %d keys",
+			budget.bytes.Load(), budget.keys.Load(), stats.Bytes, stats.Keys)
+	}
+}
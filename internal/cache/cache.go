@@ -2,11 +2,16 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"cache-service/internal/evictors"
 	"cache-service/internal/telemetry"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const (
@@ -14,27 +19,57 @@ const (
 	DefaultMaxSize    = 1024 * 1024 * 1024 // 1 GB
 	DefaultMaxKeys    = 2_000_000          // 2 million keys
 	DefaultTTL        = 30 * time.Minute
+
+	DefaultSweepInterval = time.Second
+
+	// reclaimAttempts bounds how often a write that did not fit reclaims space from other shards
+	reclaimAttempts = 3
+
+	// sweepBatchSize bounds how many expired items a shard removes while holding its lock,
+	// the sweeper releases the lock between batches so writers are not starved
+	sweepBatchSize = 512
+)
+
+const (
+	// DefaultExpiration stores an item with the cache wide TTL set through WithTTL
+	DefaultExpiration time.Duration = 0
+
+	// NoExpiration stores an item that never expires,
+	// it only leaves the cache when it is deleted or evicted
+	NoExpiration time.Duration = -1
 )
 
 // Cache is a sharded in-memory cache.
 type Cache struct {
 	shardManager   *shardManager
 	ttl            time.Duration
+	sweepInterval  time.Duration
 	maxSize        int64
 	maxKeys        int
+	accounting     SizeAccounting
 	shardCount     int
 	evictorFactory func() evictors.Evictor
 	metrics        *telemetry.CacheMetrics
+	onEvict        EvictionFunc
+	tracer         trace.Tracer
+	loads          *loadGroup    // loads of GetOrLoad and background refreshes in flight
+	negativeTTL    time.Duration // how long a loader error is remembered, 0 disables it
+	staleFor       time.Duration // how long a value is served stale after its ttl ran out
+	refresh        RefreshFunc   // optional, refreshes stale values in the background
+	earlyRefresh   float64       // beta of the early refresh, 0 disables it
 }
 
 // NewCache constructs a Cache instance using the provided options
 func NewCache(ctx context.Context, opts ...CacheOption) (*Cache, error) {
 	c := &Cache{
 		ttl:            DefaultTTL,
+		sweepInterval:  DefaultSweepInterval,
 		maxSize:        DefaultMaxSize,
 		maxKeys:        DefaultMaxKeys,
 		shardCount:     DefaultShardCount,
 		evictorFactory: func() evictors.Evictor { return evictors.NewLRUEvictor() },
+		tracer:         noop.NewTracerProvider().Tracer(tracerName),
+		loads:          newLoadGroup(),
 	}
 
 	for _, opt := range opts {
@@ -43,28 +78,228 @@ func NewCache(ctx context.Context, opts ...CacheOption) (*Cache, error) {
 		}
 	}
 
-	maxSizePerShard := c.maxSize / int64(c.shardCount)
-	maxKeysPerShard := c.maxKeys / c.shardCount
-
-	shardManagerInstance, err := newShardManager(ctx, c.shardCount, c.ttl, maxSizePerShard, maxKeysPerShard, c.evictorFactory, c.metrics)
+	shardManagerInstance, err := newShardManager(ctx, c.shardCount, c.ttl, c.staleFor, c.maxSize, c.maxKeys, c.accounting, c.evictorFactory, c.metrics, c.onEvict)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create shard manager: %w", err)
 	}
 
 	c.shardManager = shardManagerInstance
+
+	registration, err := c.metrics.ObserveShards(c.shardStats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to observe shards: %w", err)
+	}
+	// The cache is done once its context is cancelled, stop reporting its shards from then on
+	context.AfterFunc(ctx, func() { registration.Unregister() })
+
+	// The sweeper runs until the context passed to NewCache is cancelled
+	go c.runExpirySweeper(ctx)
+
 	return c, nil
 }
 
 func (c *Cache) Get(key string) ([]byte, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with a span that is a child of the span in ctx, if any.
+func (c *Cache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	entry, err := c.GetWithMeta(ctx, key)
+	return entry.Value, err
+}
+
+// Entry is a value read with GetWithMeta
+type Entry struct {
+	Value []byte
+
+	// Version identifies this value of the key, pass it to CompareAndSet to replace the value
+	// only if no one else wrote the key in the meantime
+	Version uint64
+
+	// Stale is set when the value outlived its TTL and is served within the window set with WithStaleWhileRevalidate
+	Stale bool
+}
+
+// GetWithMeta is GetContext that also returns the version of the value and whether it is stale.
+// Reading a stale value, or a value picked for an early refresh with WithEarlyRefresh,
+// refreshes it in the background through the loader set with WithRefreshLoader.
+func (c *Cache) GetWithMeta(ctx context.Context, key string) (Entry, error) {
+	return c.getEntry(ctx, key, nil)
+}
+
+// getEntry reads the key and refreshes it in the background with loader when it is stale or due for an early refresh.
+// A nil loader refreshes with the cache's refresh loader, if any.
+func (c *Cache) getEntry(ctx context.Context, key string, loader LoaderFunc) (Entry, error) {
+	ctx, span := c.tracer.Start(ctx, "cache.Get")
+	defer span.End()
+
+	start := time.Now()
 	shard := c.shardManager.GetShard(key)
-	val, err := shard.get(key)
-	return val, err
+	item, err := shard.lookup(key)
+	recordGetLatency(shard.ctx, c.metrics, start, err)
+	if err != nil {
+		endGetSpan(span, shard.id, Entry{}, false, err)
+		return Entry{}, err
+	}
+
+	entry := Entry{Value: item.Value, Version: item.Version, Stale: item.isStale(start)}
+	refreshing := (entry.Stale || c.refreshEarly(item, start)) && c.refreshInBackground(ctx, key, loader)
+
+	endGetSpan(span, shard.id, entry, refreshing, nil)
+	return entry, nil
 }
 
 func (c *Cache) Set(key string, value []byte) error {
+	_, err := c.SetWithTTL(key, value, DefaultExpiration)
+	return err
+}
+
+// SetWithTTL stores the value with a TTL specific to this key and returns the version of the new value.
+// Pass DefaultExpiration to use the cache wide TTL or NoExpiration to keep the item until it is deleted or evicted.
+func (c *Cache) SetWithTTL(key string, value []byte, ttl time.Duration) (uint64, error) {
+	return c.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext is SetWithTTL with a span that is a child of the span in ctx, if any.
+func (c *Cache) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, error) {
+	return c.setVersioned(ctx, key, value, write{ttl: ttl})
+}
+
+// SetWithStaleWindow is SetWithTTL with a stale window specific to this key, see WithStaleWhileRevalidate.
+// The value is served stale for staleFor after ttl ran out and removed after that.
+// Pass DefaultExpiration to use the cache wide window or NoExpiration to keep the stale value until it is deleted or evicted.
+func (c *Cache) SetWithStaleWindow(key string, value []byte, ttl, staleFor time.Duration) (uint64, error) {
+	return c.SetWithStaleWindowContext(context.Background(), key, value, ttl, staleFor)
+}
+
+// SetWithStaleWindowContext is SetWithStaleWindow with a span that is a child of the span in ctx, if any.
+func (c *Cache) SetWithStaleWindowContext(ctx context.Context, key string, value []byte, ttl, staleFor time.Duration) (uint64, error) {
+	return c.setVersioned(ctx, key, value, write{ttl: ttl, staleFor: staleFor})
+}
+
+// setVersioned stores the value as described by w and returns the version of the stored item
+func (c *Cache) setVersioned(ctx context.Context, key string, value []byte, w write) (uint64, error) {
+	item, err := c.set(ctx, key, value, w)
+	if err != nil {
+		return 0, err
+	}
+	return item.Version, nil
+}
+
+// set stores the value as described by w and returns the stored item
+func (c *Cache) set(ctx context.Context, key string, value []byte, w write) (*cacheItem, error) {
+	_, span := c.tracer.Start(ctx, "cache.Set")
+	defer span.End()
+
+	if (w.ttl < 0 && w.ttl != NoExpiration) || (w.staleFor < 0 && w.staleFor != NoExpiration) {
+		span.SetStatus(codes.Error, ErrInvalidTTL.Error())
+		return nil, ErrInvalidTTL
+	}
+
+	// A load of the key that is still running would otherwise store an older value over this one
+	if w.load == nil {
+		c.loads.supersede(key)
+	}
+
+	start := time.Now()
+	shard := c.shardManager.GetShard(key)
+	evicted, item, err := c.put(shard, key, value, w)
+	recordSetLatency(shard.ctx, c.metrics, start, err)
+
+	if item != nil {
+		value = item.Value
+	}
+	endSetSpan(span, shard.id, value, evicted, err)
+	return item, err
+}
+
+// put stores the value in its shard. When the shard is within its fair share but the cache is full,
+// the space is reclaimed from the largest shards and the write is retried,
+// so a write is only rejected with ErrCacheFull when nothing is left to evict.
+// Returns the number of items removed to make space and the stored item.
+func (c *Cache) put(shard *cacheShard, key string, value []byte, w write) (int, *cacheItem, error) {
+	evicted, item, err := shard.put(key, value, w)
+
+	// Other writers may take the reclaimed space before the retry, so give up after a few rounds
+	for attempt := 0; errors.Is(err, ErrCacheFull) && attempt < reclaimAttempts; attempt++ {
+		valueSize := len(value)
+		if w.increment {
+			valueSize = maxCounterLength
+		}
+		if overBy := c.shardManager.budget.overBy(shard.itemSize(key, valueSize)); overBy > 0 {
+			removed := c.shardManager.reclaim(overBy)
+			if removed == 0 {
+				break
+			}
+			evicted += removed
+		}
+
+		var shardEvicted int
+		shardEvicted, item, err = shard.put(key, value, w)
+		evicted += shardEvicted
+	}
+
+	if errors.Is(err, ErrCacheFull) {
+		recordError(shard.ctx, c.metrics, ErrCacheFull)
+	}
+	return evicted, item, err
+}
+
+// Delete removes the key from the cache.
+// Returns ErrNotFound if the key does not exist or has already expired.
+func (c *Cache) Delete(key string) error {
+	// A load of the key that is still running must not bring the deleted key back
+	c.loads.supersede(key)
+
 	shard := c.shardManager.GetShard(key)
-	if err := shard.set(key, value); err != nil {
-		return err
+	return shard.remove(key)
+}
+
+// shardStats is called by the metric gauges on every collection.
+// The capacity comes from the budget, like in Stats, as the fair shares of the shards can round down.
+func (c *Cache) shardStats() telemetry.CacheStats {
+	stats := telemetry.CacheStats{
+		MaxBytes: c.shardManager.budget.maxBytes,
+		MaxKeys:  int(c.shardManager.budget.maxKeys),
+		Shards:   make([]telemetry.ShardStats, 0, len(c.shardManager.shards)),
+	}
+	for _, shard := range c.shardManager.shards {
+		shardStats := shard.stats()
+		stats.Shards = append(stats.Shards, telemetry.ShardStats{
+			ID:        shardStats.ID,
+			Items:     shardStats.Keys,
+			SizeBytes: shardStats.Bytes,
+		})
+	}
+	return stats
+}
+
+// runExpirySweeper periodically removes expired items from every shard,
+// so keys that are written and never read again do not hold on to memory until the next eviction.
+// It returns when ctx is cancelled.
+func (c *Cache) runExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(c.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.sweepExpired(ctx)
+		}
+	}
+}
+
+func (c *Cache) sweepExpired(ctx context.Context) {
+	c.loads.forgetExpiredFailures()
+
+	for _, shard := range c.shardManager.shards {
+		// Keep draining the shard while there are full batches of expired items
+		for shard.removeExpired(sweepBatchSize) == sweepBatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
 	}
-	return nil
 }
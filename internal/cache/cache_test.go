@@ -53,6 +53,90 @@ func TestCacheExpiration(t *testing.T) {
 	}
 }
 
+func TestCacheSetWithTTL(t *testing.T) {
+	metrics := createTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithTTL(time.Minute), WithMetrics(metrics))
+
+	if _, err := cacheInstance.SetWithTTL("short", []byte("v"), 10*time.Millisecond); err != nil {
+		t.Fatalf(setErrStr, err)
+	}
+	if _, err := cacheInstance.SetWithTTL("forever", []byte("v"), NoExpiration); err != nil {
+		t.Fatalf(setErrStr, err)
+	}
+	if _, err := cacheInstance.SetWithTTL("default", []byte("v"), DefaultExpiration); err != nil {
+		t.Fatalf(setErrStr, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := cacheInstance.Get("short"); err != ErrExpired {
+		t.Fatalf("expected short lived key to expire, got %v", err)
+	}
+	if _, err := cacheInstance.Get("forever"); err != nil {
+		t.Fatalf("expected non expiring key to be present, got %v", err)
+	}
+	if _, err := cacheInstance.Get("default"); err != nil {
+		t.Fatalf("expected key with default ttl to be present, got %v", err)
+	}
+}
+
+func TestCacheSetWithInvalidTTL(t *testing.T) {
+	metrics := createTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(metrics))
+
+	if _, err := cacheInstance.SetWithTTL("k", []byte("v"), -5*time.Second); err != ErrInvalidTTL {
+		t.Fatalf("expected ErrInvalidTTL, got %v", err)
+	}
+}
+
+func TestCacheExpirySweeper(t *testing.T) {
+	metrics := createTestMetrics(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cacheInstance, _ := NewCache(ctx, WithShardCount(4), WithTTL(10*time.Millisecond), WithExpirySweepInterval(5*time.Millisecond), WithMetrics(metrics))
+
+	for i := range 100 {
+		if err := cacheInstance.Set(fmt.Sprintf("key-%d", i), []byte("v")); err != nil {
+			t.Fatalf(setErrStr, err)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// The keys are never read, so only the sweeper could have removed them
+	for _, shard := range cacheInstance.shardManager.shards {
+		shard.mu.RLock()
+		remaining := len(shard.items)
+		shard.mu.RUnlock()
+
+		if remaining != 0 {
+			t.Fatalf("expected sweeper to remove expired keys, %d left in shard %s", remaining, shard.id)
+		}
+	}
+}
+
+func TestCacheExpirySweeperStopsOnCancel(t *testing.T) {
+	metrics := createTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithExpirySweepInterval(time.Millisecond), WithMetrics(metrics))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		cacheInstance.runExpirySweeper(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected sweeper to stop after the context was cancelled")
+	}
+}
+
 func TestCacheGetNotFound(t *testing.T) {
 	metrics := createTestMetrics(t)
 	cacheInstance, _ := NewCache(context.Background(), WithMetrics(metrics))
@@ -62,6 +146,27 @@ func TestCacheGetNotFound(t *testing.T) {
 	}
 }
 
+func TestCacheDelete(t *testing.T) {
+	metrics := createTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(metrics))
+
+	if err := cacheInstance.Set("a", []byte("b")); err != nil {
+		t.Fatalf(setErrStr, err)
+	}
+
+	if err := cacheInstance.Delete("a"); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	if _, err := cacheInstance.Get("a"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	if err := cacheInstance.Delete("a"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound on second delete, got %v", err)
+	}
+}
+
 func TestCacheSetInvalidValue(t *testing.T) {
 	metrics := createTestMetrics(t)
 	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMetrics(metrics))
@@ -97,6 +202,60 @@ func TestCacheSetMaxKeysExceeded(t *testing.T) {
 	}
 }
 
+func TestCacheWithLFUEvictor(t *testing.T) {
+	metrics := createTestMetrics(t)
+
+	cacheInstance, _ := NewCache(
+		context.Background(),
+		WithShardCount(1),
+		WithMaxSize(3),
+		WithEvictorFactory(func() evictors.Evictor { return evictors.NewLFUEvictor() }),
+		WithMetrics(metrics))
+
+	cacheInstance.Set("hot", []byte("1"))
+	cacheInstance.Get("hot")
+	cacheInstance.Get("hot")
+
+	// A scan of one-off keys keeps the frequently read key in the cache
+	for i := range 10 {
+		if err := cacheInstance.Set(fmt.Sprintf("scan-%d", i), []byte("1")); err != nil {
+			t.Fatalf(setErrStr, err)
+		}
+	}
+
+	if _, err := cacheInstance.Get("hot"); err != nil {
+		t.Fatalf("expected hot key to survive the scan, got %v", err)
+	}
+}
+
+func TestCacheEvictionSeesBufferedReads(t *testing.T) {
+	metrics := createTestMetrics(t)
+
+	cacheInstance, _ := NewCache(
+		context.Background(),
+		WithShardCount(1),
+		WithMaxSize(3),
+		WithEvictorFactory(func() evictors.Evictor { return evictors.NewLRUEvictor() }),
+		WithMetrics(metrics))
+
+	cacheInstance.Set("a", []byte("1"))
+	cacheInstance.Set("b", []byte("1"))
+	cacheInstance.Set("c", []byte("1"))
+
+	// Far fewer reads than fill a stripe of the read buffer
+	for range 10 {
+		cacheInstance.Get("a")
+	}
+	cacheInstance.Set("d", []byte("1"))
+
+	if _, err := cacheInstance.Get("a"); err != nil {
+		t.Fatalf("expected the recently read key to survive the eviction, got %v", err)
+	}
+	if _, err := cacheInstance.Get("b"); err != ErrNotFound {
+		t.Fatalf("expected the least recently used key to be evicted, got %v", err)
+	}
+}
+
 func TestCacheConcurrency(t *testing.T) {
 	metrics := createTestMetrics(t)
 	cache, err := NewCache(context.Background(), WithMetrics(metrics))
@@ -136,3 +295,29 @@ func TestCacheConcurrency(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestCacheOnEvictCanCallBackIntoCache(t *testing.T) {
+	metrics := createTestMetrics(t)
+
+	var cacheInstance *Cache
+	onEvict := func(key string, value []byte, reason EvictionReason) {
+		// Runs after the shard lock is released, so writing to the same shard must not deadlock
+		if reason == EvictedDeleted {
+			cacheInstance.Set("evicted:"+key, value)
+		}
+	}
+
+	cacheInstance, err := NewCache(context.Background(), WithShardCount(1), WithOnEvict(onEvict), WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("new cache error: %v", err)
+	}
+
+	cacheInstance.Set("a", []byte("1"))
+	if err := cacheInstance.Delete("a"); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	if value, err := cacheInstance.Get("evicted:a"); err != nil || string(value) != "1" {
+		t.Fatalf("expected callback to write the deleted value back, got %s %v", value, err)
+	}
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// writeCondition decides whether a write is applied, given the item currently stored under its key
+type writeCondition int
+
+const (
+	// always stores the value whatever is stored under the key
+	always writeCondition = iota
+
+	// ifVersion only replaces the value stored with the expected version
+	ifVersion
+
+	// ifAbsent only stores the value if the key is missing, expired or stale (NX)
+	ifAbsent
+
+	// ifPresent only replaces a value that is stored and fresh (XX)
+	ifPresent
+
+	// unlessSuperseded stores a loaded value unless the key was written or deleted while it loaded
+	unlessSuperseded
+)
+
+// Conditions of SetIfAbsent and SetIfPresent that did not hold, they are reported as false rather than an error
+var (
+	errKeyExists  = errors.New("cache: key exists")
+	errKeyMissing = errors.New("cache: key missing")
+)
+
+// errSuperseded is returned for a loaded value that is older than a write or delete of its key
+var errSuperseded = errors.New("cache: load superseded")
+
+// check returns the error the write fails with, current is nil when the key is missing or expired
+func (w write) check(current *cacheItem) error {
+	switch w.condition {
+	case ifVersion:
+		if current == nil || current.Version != w.version {
+			return ErrVersionMismatch
+		}
+	case ifAbsent:
+		// A stale value is only kept to be served while it is refreshed, its key counts as absent
+		if current != nil && !current.isStale(time.Now()) {
+			return errKeyExists
+		}
+	case ifPresent:
+		if current == nil || current.isStale(time.Now()) {
+			return errKeyMissing
+		}
+	case unlessSuperseded:
+		if w.load.superseded.Load() {
+			return errSuperseded
+		}
+	}
+	return nil
+}
+
+// isConditionFailure reports whether a write failed because its condition did not hold,
+// which is an expected outcome of a conditional write rather than an error
+func isConditionFailure(err error) bool {
+	return errors.Is(err, ErrVersionMismatch) || errors.Is(err, errKeyExists) || errors.Is(err, errKeyMissing) ||
+		errors.Is(err, errSuperseded)
+}
+
+// CompareAndSet replaces the value of key only if it is still stored with expectedVersion, as returned by GetWithMeta.
+// It fails with ErrVersionMismatch if the key was written since or is missing, so concurrent writers
+// that read, modify and write the same key do not lose each other's updates. Returns the version of the new value.
+func (c *Cache) CompareAndSet(key string, value []byte, expectedVersion uint64) (uint64, error) {
+	return c.CompareAndSetContext(context.Background(), key, value, DefaultExpiration, expectedVersion)
+}
+
+// CompareAndSetContext is CompareAndSet with a TTL like SetWithTTL and a span that is a child of the span in ctx, if any.
+func (c *Cache) CompareAndSetContext(ctx context.Context, key string, value []byte, ttl time.Duration, expectedVersion uint64) (uint64, error) {
+	return c.setVersioned(ctx, key, value, write{ttl: ttl, condition: ifVersion, version: expectedVersion})
+}
+
+// SetIfAbsent stores the value only if the key is missing or expired, like SET NX in Redis.
+// A stale value counts as absent, so a lock held by a stale value can be taken.
+// The check and the write happen under the shard lock, so of many concurrent callers exactly one stores its value,
+// which makes it suitable for idempotency keys and simple locks.
+// Returns the version of the new value and whether the value was stored.
+func (c *Cache) SetIfAbsent(key string, value []byte) (uint64, bool, error) {
+	return c.SetIfAbsentContext(context.Background(), key, value, DefaultExpiration)
+}
+
+// SetIfAbsentContext is SetIfAbsent with a TTL like SetWithTTL and a span that is a child of the span in ctx, if any.
+func (c *Cache) SetIfAbsentContext(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, bool, error) {
+	return c.setIf(ctx, key, value, write{ttl: ttl, condition: ifAbsent})
+}
+
+// SetIfPresent replaces the value only if the key is stored and not expired, like SET XX in Redis.
+// A stale value counts as absent. Returns the version of the new value and whether the value was stored.
+func (c *Cache) SetIfPresent(key string, value []byte) (uint64, bool, error) {
+	return c.SetIfPresentContext(context.Background(), key, value, DefaultExpiration)
+}
+
+// SetIfPresentContext is SetIfPresent with a TTL like SetWithTTL and a span that is a child of the span in ctx, if any.
+func (c *Cache) SetIfPresentContext(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, bool, error) {
+	return c.setIf(ctx, key, value, write{ttl: ttl, condition: ifPresent})
+}
+
+// setIf stores the value if the condition of w holds and reports whether it did, with the version of the new value
+func (c *Cache) setIf(ctx context.Context, key string, value []byte, w write) (uint64, bool, error) {
+	version, err := c.setVersioned(ctx, key, value, w)
+	if isConditionFailure(err) {
+		return 0, false, nil
+	}
+	return version, err == nil, err
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetWithMetaVersionIncreasesWithEveryWrite(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	var last uint64
+	for i := range 3 {
+		cacheInstance.Set("key", []byte{byte(i + 1)})
+
+		entry, err := cacheInstance.GetWithMeta(context.Background(), "key")
+		if err != nil {
+			t.Fatalf(unexpectedErrStr, err)
+		}
+		if entry.Version <= last {
+			t.Fatalf("expected version to increase past %d, got %d", last, entry.Version)
+		}
+		last = entry.Version
+	}
+
+	// A key that is deleted and written again does not get an old version back
+	cacheInstance.Delete("key")
+	cacheInstance.Set("key", []byte("v"))
+	if entry, _ := cacheInstance.GetWithMeta(context.Background(), "key"); entry.Version <= last {
+		t.Fatalf("expected a recreated key to get a new version, got %d after %d", entry.Version, last)
+	}
+}
+
+func TestWritesReturnTheVersionTheyStored(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	writes := []func() (uint64, error){
+		func() (uint64, error) { return cacheInstance.SetWithTTL("key", []byte("v1"), DefaultExpiration) },
+		func() (uint64, error) {
+			version, _, err := cacheInstance.SetIfPresent("key", []byte("v2"))
+			return version, err
+		},
+		func() (uint64, error) {
+			cacheInstance.Delete("key")
+			version, _, err := cacheInstance.SetIfAbsent("key", []byte("v3"))
+			return version, err
+		},
+	}
+	for i, write := range writes {
+		version, err := write()
+		if err != nil {
+			t.Fatalf(unexpectedErrStr, err)
+		}
+		if entry, _ := cacheInstance.GetWithMeta(context.Background(), "key"); version == 0 || entry.Version != version {
+			t.Fatalf("expected write %d to return the stored version %d, got %d", i, entry.Version, version)
+		}
+	}
+
+	// A conditional write that is not applied has no version
+	if version, stored, _ := cacheInstance.SetIfAbsent("key", []byte("v4")); stored || version != 0 {
+		t.Fatalf("expected no version for a write that was not stored, got %d", version)
+	}
+}
+
+func TestCompareAndSet(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	cacheInstance.Set("key", []byte("v1"))
+	entry, _ := cacheInstance.GetWithMeta(context.Background(), "key")
+
+	version, err := cacheInstance.CompareAndSet("key", []byte("v2"), entry.Version)
+	if err != nil {
+		t.Fatalf("expected the write with the current version to succeed, got %v", err)
+	}
+	if version <= entry.Version {
+		t.Fatalf("expected a new version, got %d after %d", version, entry.Version)
+	}
+
+	if _, err := cacheInstance.CompareAndSet("key", []byte("v3"), entry.Version); err != ErrVersionMismatch {
+		t.Fatalf("expected ErrVersionMismatch for an outdated version, got %v", err)
+	}
+	if value, _ := cacheInstance.Get("key"); string(value) != "v2" {
+		t.Fatalf("expected the failed write to leave v2, got %q", value)
+	}
+
+	if _, err := cacheInstance.CompareAndSet("missing", []byte("v"), version); err != ErrVersionMismatch {
+		t.Fatalf("expected ErrVersionMismatch for a missing key, got %v", err)
+	}
+	if _, err := cacheInstance.Get("missing"); err != ErrNotFound {
+		t.Fatalf("expected the failed write not to create the key, got %v", err)
+	}
+}
+
+func TestCompareAndSetExpiredKey(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	cacheInstance.SetWithTTL("key", []byte("v"), 10*time.Millisecond)
+	entry, _ := cacheInstance.GetWithMeta(context.Background(), "key")
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := cacheInstance.CompareAndSet("key", []byte("v2"), entry.Version); err != ErrVersionMismatch {
+		t.Fatalf("expected ErrVersionMismatch for an expired key, got %v", err)
+	}
+}
+
+func TestCompareAndSetConcurrentIncrements(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	cacheInstance.Set("counter", []byte("0"))
+
+	const writers, increments = 8, 100
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range increments {
+				// Retry the read-modify-write until no other writer got in between
+				for {
+					entry, _ := cacheInstance.GetWithMeta(context.Background(), "counter")
+					n, _ := strconv.Atoi(string(entry.Value))
+					if _, err := cacheInstance.CompareAndSet("counter", []byte(strconv.Itoa(n+1)), entry.Version); err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, _ := cacheInstance.Get("counter")
+	if expected := fmt.Sprint(writers * increments); string(value) != expected {
+		t.Fatalf("expected no lost updates and a count of %s, got %s", expected, value)
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	if _, stored, err := cacheInstance.SetIfAbsent("key", []byte("first")); !stored || err != nil {
+		t.Fatalf("expected a missing key to be stored, got %v, %v", stored, err)
+	}
+	if _, stored, err := cacheInstance.SetIfAbsent("key", []byte("second")); stored || err != nil {
+		t.Fatalf("expected an existing key not to be overwritten, got %v, %v", stored, err)
+	}
+	if value, _ := cacheInstance.Get("key"); string(value) != "first" {
+		t.Fatalf("expected the first value to be kept, got %q", value)
+	}
+
+	if _, _, err := cacheInstance.SetIfAbsent("empty", nil); err != ErrInvalidValue {
+		t.Fatalf("expected errors other than the condition to be returned, got %v", err)
+	}
+}
+
+func TestSetIfAbsentTreatsExpiredKeyAsAbsent(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	cacheInstance.SetWithTTL("key", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, stored, _ := cacheInstance.SetIfAbsent("key", []byte("new")); !stored {
+		t.Fatalf("expected an expired key to count as absent")
+	}
+	if value, _ := cacheInstance.Get("key"); string(value) != "new" {
+		t.Fatalf("expected the new value, got %q", value)
+	}
+}
+
+func TestSetIfAbsentAndIfPresentTreatStaleKeyAsAbsent(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithStaleWhileRevalidate(time.Minute), WithMetrics(createTestMetrics(t)))
+	cacheInstance.SetWithTTL("lock", []byte("owner-1"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, stored, _ := cacheInstance.SetIfPresent("lock", []byte("owner-1")); stored {
+		t.Fatalf("expected a stale key not to be replaced by SetIfPresent")
+	}
+	if _, stored, err := cacheInstance.SetIfAbsent("lock", []byte("owner-2")); !stored || err != nil {
+		t.Fatalf("expected a stale key to be taken over by SetIfAbsent, got %v, %v", stored, err)
+	}
+	if entry, _ := cacheInstance.GetWithMeta(context.Background(), "lock"); entry.Stale || string(entry.Value) != "owner-2" {
+		t.Fatalf("expected a fresh owner-2, got %q stale=%v", entry.Value, entry.Stale)
+	}
+}
+
+func TestSetIfAbsentConcurrentCallers(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	var stored atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, _ := cacheInstance.SetIfAbsentContext(context.Background(), "lock", []byte(strconv.Itoa(i)), time.Minute); ok {
+				stored.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := stored.Load(); n != 1 {
+		t.Fatalf("expected exactly one caller to take the lock, got %d", n)
+	}
+}
+
+func TestSetIfPresent(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	if _, stored, err := cacheInstance.SetIfPresent("key", []byte("v")); stored || err != nil {
+		t.Fatalf("expected a missing key not to be created, got %v, %v", stored, err)
+	}
+	if _, err := cacheInstance.Get("key"); err != ErrNotFound {
+		t.Fatalf("expected the key to stay missing, got %v", err)
+	}
+
+	cacheInstance.Set("key", []byte("old"))
+	if _, stored, _ := cacheInstance.SetIfPresentContext(context.Background(), "key", []byte("new"), 10*time.Millisecond); !stored {
+		t.Fatalf("expected an existing key to be replaced")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, stored, _ := cacheInstance.SetIfPresent("key", []byte("newer")); stored {
+		t.Fatalf("expected an expired key to count as absent")
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+)
+
+// maxCounterLength is the length of the longest decimal int64, math.MinInt64
+const maxCounterLength = len("-9223372036854775808")
+
+// Incr adds 1 to the integer stored under key, see IncrBy.
+func (c *Cache) Incr(key string) (int64, error) {
+	return c.IncrBy(key, 1, DefaultExpiration)
+}
+
+// Decr subtracts 1 from the integer stored under key, see IncrBy.
+func (c *Cache) Decr(key string) (int64, error) {
+	return c.IncrBy(key, -1, DefaultExpiration)
+}
+
+// IncrBy adds delta to the integer stored under key and returns the result, like INCRBY in Redis.
+// The value is stored as a decimal string, so it reads back with Get like any other value.
+// A missing, expired or stale key counts as 0 and is created with ttlIfCreated, which follows SetWithTTL,
+// while an existing counter keeps its expiry. The read and the write happen under the shard lock,
+// so concurrent increments are never lost. Fails with ErrNotInteger if the stored value is not
+// a decimal int64 and with ErrOverflow if the result does not fit, leaving the value unchanged.
+func (c *Cache) IncrBy(key string, delta int64, ttlIfCreated time.Duration) (int64, error) {
+	return c.IncrByContext(context.Background(), key, delta, ttlIfCreated)
+}
+
+// DecrBy subtracts delta from the integer stored under key, see IncrBy.
+func (c *Cache) DecrBy(key string, delta int64, ttlIfCreated time.Duration) (int64, error) {
+	// -math.MinInt64 does not fit in an int64
+	if delta == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+	return c.IncrBy(key, -delta, ttlIfCreated)
+}
+
+// IncrByContext is IncrBy with a span that is a child of the span in ctx, if any.
+func (c *Cache) IncrByContext(ctx context.Context, key string, delta int64, ttlIfCreated time.Duration) (int64, error) {
+	item, err := c.set(ctx, key, nil, write{ttl: ttlIfCreated, increment: true, delta: delta})
+	if err != nil {
+		return 0, err
+	}
+	// The value was formatted by incremented, it always parses
+	n, _ := strconv.ParseInt(string(item.Value), 10, 64)
+	return n, nil
+}
+
+// incremented returns the value of current plus delta, current is nil when the key is missing or expired
+func incremented(current *cacheItem, delta int64) ([]byte, error) {
+	var n int64
+	if current != nil {
+		var err error
+		if n, err = strconv.ParseInt(string(current.Value), 10, 64); err != nil {
+			return nil, ErrNotInteger
+		}
+	}
+
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return nil, ErrOverflow
+	}
+	return strconv.AppendInt(make([]byte, 0, maxCounterLength), n+delta, 10), nil
+}
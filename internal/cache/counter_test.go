@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrAndDecr(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	if n, err := cacheInstance.Incr("counter"); err != nil || n != 1 {
+		t.Fatalf("expected a missing key to count from 0, got %d, %v", n, err)
+	}
+	if n, err := cacheInstance.IncrBy("counter", 41, DefaultExpiration); err != nil || n != 42 {
+		t.Fatalf("expected 42, got %d, %v", n, err)
+	}
+	if n, err := cacheInstance.DecrBy("counter", 50, DefaultExpiration); err != nil || n != -8 {
+		t.Fatalf("expected -8, got %d, %v", n, err)
+	}
+	if n, err := cacheInstance.Decr("counter"); err != nil || n != -9 {
+		t.Fatalf("expected -9, got %d, %v", n, err)
+	}
+
+	// The counter is stored as a decimal string, and a value set that way can be incremented
+	if value, _ := cacheInstance.Get("counter"); string(value) != "-9" {
+		t.Fatalf("expected the counter to read back as \"-9\", got %q", value)
+	}
+	cacheInstance.Set("counter", []byte("100"))
+	if n, err := cacheInstance.Incr("counter"); err != nil || n != 101 {
+		t.Fatalf("expected 101, got %d, %v", n, err)
+	}
+}
+
+func TestIncrVersionsTheValue(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	cacheInstance.Incr("counter")
+	before, _ := cacheInstance.GetWithMeta(context.Background(), "counter")
+
+	cacheInstance.Incr("counter")
+	if _, err := cacheInstance.CompareAndSet("counter", []byte("0"), before.Version); err != ErrVersionMismatch {
+		t.Fatalf("expected an increment to change the version, got %v", err)
+	}
+}
+
+func TestIncrTTL(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	if _, err := cacheInstance.IncrBy("counter", 1, 50*time.Millisecond); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	// Only the increment that creates the counter sets its TTL
+	if n, err := cacheInstance.IncrBy("counter", 1, NoExpiration); err != nil || n != 2 {
+		t.Fatalf("expected 2, got %d, %v", n, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cacheInstance.Get("counter"); err != ErrExpired {
+		t.Fatalf("expected the counter to keep the TTL it was created with, got %v", err)
+	}
+
+	// An expired counter starts over with a new TTL
+	if n, err := cacheInstance.IncrBy("counter", 5, NoExpiration); err != nil || n != 5 {
+		t.Fatalf("expected an expired counter to count from 0, got %d, %v", n, err)
+	}
+
+	if _, err := cacheInstance.IncrBy("counter", 1, -2*time.Second); err != ErrInvalidTTL {
+		t.Fatalf("expected ErrInvalidTTL, got %v", err)
+	}
+}
+
+func TestIncrStaleCounterStartsOver(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithStaleWhileRevalidate(time.Minute), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.IncrBy("counter", 10, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if n, err := cacheInstance.IncrBy("counter", 1, time.Minute); err != nil || n != 1 {
+		t.Fatalf("expected a stale counter to count from 0, got %d, %v", n, err)
+	}
+	if entry, _ := cacheInstance.GetWithMeta(context.Background(), "counter"); entry.Stale {
+		t.Fatalf("expected the new counter to be fresh with the TTL of the increment")
+	}
+}
+
+func TestIncrNotInteger(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	for _, value := range []string{"abc", "1.5", " 1", "0x10", "9223372036854775808"} {
+		cacheInstance.Set("key", []byte(value))
+		if _, err := cacheInstance.Incr("key"); err != ErrNotInteger {
+			t.Fatalf("expected ErrNotInteger for %q, got %v", value, err)
+		}
+		if stored, _ := cacheInstance.Get("key"); string(stored) != value {
+			t.Fatalf("expected the failed increment to leave %q, got %q", value, stored)
+		}
+	}
+}
+
+func TestIncrOverflow(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.Set("max", []byte(strconv.FormatInt(math.MaxInt64, 10)))
+	if _, err := cacheInstance.Incr("max"); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow past MaxInt64, got %v", err)
+	}
+	if n, err := cacheInstance.Decr("max"); err != nil || n != math.MaxInt64-1 {
+		t.Fatalf("expected MaxInt64-1, got %d, %v", n, err)
+	}
+
+	cacheInstance.Set("min", []byte(strconv.FormatInt(math.MinInt64, 10)))
+	if _, err := cacheInstance.Decr("min"); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow past MinInt64, got %v", err)
+	}
+	if n, err := cacheInstance.IncrBy("zero", math.MinInt64, DefaultExpiration); err != nil || n != math.MinInt64 {
+		t.Fatalf("expected MinInt64, got %d, %v", n, err)
+	}
+	if _, err := cacheInstance.DecrBy("other", math.MinInt64, DefaultExpiration); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow when negating MinInt64, got %v", err)
+	}
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(4), WithMetrics(createTestMetrics(t)))
+
+	const (
+		goroutines = 64
+		increments = 1000
+	)
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range increments {
+				// Half of the goroutines add 3 and take 1 away, the other half add 1, all on the same keys
+				if g%2 == 0 {
+					cacheInstance.IncrBy("counter", 3, DefaultExpiration)
+					cacheInstance.DecrBy("counter", 1, DefaultExpiration)
+				} else {
+					cacheInstance.Incr("counter")
+				}
+				cacheInstance.Incr("key-" + strconv.Itoa(i%10))
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := int64(goroutines/2*increments*2 + goroutines/2*increments)
+	if n, err := cacheInstance.IncrBy("counter", 0, DefaultExpiration); err != nil || n != want {
+		t.Fatalf("expected no increment to be lost, got %d, want %d, %v", n, want, err)
+	}
+	for i := range 10 {
+		value, _ := cacheInstance.Get("key-" + strconv.Itoa(i))
+		if want := strconv.Itoa(goroutines * increments / 10); string(value) != want {
+			t.Fatalf("expected key-%d to be %s, got %q", i, want, value)
+		}
+	}
+}
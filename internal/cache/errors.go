@@ -11,4 +11,14 @@ var (
 	ErrInvalidValue  = errors.New("cache: invalid value")
 	ErrValueTooLarge = errors.New("cache: value too large")
 	ErrTooManyKeys   = errors.New("cache: too many keys in shard")
+	ErrInvalidTTL    = errors.New("cache: invalid ttl")
+	ErrNotAdmitted   = errors.New("cache: rejected by admission policy")
+
+	ErrVersionMismatch = errors.New("cache: version mismatch")
+
+	ErrNotInteger = errors.New("cache: value is not an integer")
+	ErrOverflow   = errors.New("cache: integer overflow")
+
+	ErrInvalidLoader  = errors.New("cache: loader must not be nil")
+	ErrLoaderPanicked = errors.New("cache: loader panicked")
 )
@@ -0,0 +1,45 @@
+package cache
+
+// EvictionReason tells an eviction callback why a key left the cache
+type EvictionReason int
+
+const (
+	// EvictedCapacity means the evictor chose the key to make room for another write
+	EvictedCapacity EvictionReason = iota
+
+	// EvictedExpired means the key's TTL ran out
+	EvictedExpired
+
+	// EvictedDeleted means the key was removed through Delete
+	EvictedDeleted
+
+	// EvictedReplaced means the key was overwritten with a new value,
+	// the callback receives the old value
+	EvictedReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	case EvictedDeleted:
+		return "deleted"
+	case EvictedReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictionFunc is called with the key and value of every entry that leaves the cache
+type EvictionFunc func(key string, value []byte, reason EvictionReason)
+
+// evictedEntry is an entry removed while the shard lock was held,
+// it is handed to the eviction callback once the lock is released
+type evictedEntry struct {
+	key    string
+	value  []byte
+	reason EvictionReason
+}
@@ -0,0 +1,62 @@
+package cache
+
+import "container/heap"
+
+// notIndexed marks an item that is not tracked by the expiry heap, i.e. it never expires
+const notIndexed = -1
+
+// expiryHeap is a min-heap of items ordered by ExpiresAt, the item that expires first is at the root.
+// Each item keeps its own position in heapIndex so it can be fixed or removed in O(log n).
+// Items with a zero ExpiresAt never expire and are never pushed on the heap.
+type expiryHeap []*cacheItem
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool { return h[i].ExpiresAt.Before(h[j].ExpiresAt) }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	item := x.(*cacheItem)
+	item.heapIndex = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.heapIndex = notIndexed
+	*h = old[:n-1]
+	return item
+}
+
+// track adds the item to the heap if it has an expiry
+func (h *expiryHeap) track(item *cacheItem) {
+	if item.ExpiresAt.IsZero() {
+		item.heapIndex = notIndexed
+		return
+	}
+	heap.Push(h, item)
+}
+
+// untrack removes the item from the heap if it is on it
+func (h *expiryHeap) untrack(item *cacheItem) {
+	if item.heapIndex == notIndexed {
+		return
+	}
+	heap.Remove(h, item.heapIndex)
+}
+
+// peek returns the item that expires first without removing it
+func (h expiryHeap) peek() *cacheItem {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+)
+
+func TestExpiryHeapOrder(t *testing.T) {
+	now := time.Now()
+	var h expiryHeap
+
+	late := &cacheItem{Key: "late", ExpiresAt: now.Add(3 * time.Second)}
+	early := &cacheItem{Key: "early", ExpiresAt: now.Add(time.Second)}
+	middle := &cacheItem{Key: "middle", ExpiresAt: now.Add(2 * time.Second)}
+	never := &cacheItem{Key: "never"}
+
+	for _, item := range []*cacheItem{late, early, middle, never} {
+		h.track(item)
+	}
+
+	if h.Len() != 3 {
+		t.Fatalf("expected non expiring item to be skipped, got %d items", h.Len())
+	}
+	if never.heapIndex != notIndexed {
+		t.Fatalf("expected non expiring item to be marked as not indexed")
+	}
+
+	for _, expected := range []string{"early", "middle", "late"} {
+		item := heap.Pop(&h).(*cacheItem)
+		if item.Key != expected {
+			t.Fatalf("expected %s, got %s", expected, item.Key)
+		}
+	}
+}
+
+func TestExpiryHeapUntrack(t *testing.T) {
+	now := time.Now()
+	var h expiryHeap
+
+	first := &cacheItem{Key: "first", ExpiresAt: now.Add(time.Second)}
+	second := &cacheItem{Key: "second", ExpiresAt: now.Add(2 * time.Second)}
+	h.track(first)
+	h.track(second)
+
+	h.untrack(first)
+	h.untrack(first) // untracking twice is a no-op
+
+	if first.heapIndex != notIndexed {
+		t.Fatalf("expected untracked item to be marked as not indexed")
+	}
+	if top := h.peek(); top != second {
+		t.Fatalf("expected second to be at the top, got %v", top)
+	}
+}
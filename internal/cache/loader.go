@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoaderFunc loads the value of a key that is missing from the cache and returns the TTL to store it with.
+// The TTL follows SetWithTTL: DefaultExpiration uses the cache wide TTL, NoExpiration keeps the value until it is evicted.
+type LoaderFunc func(ctx context.Context) ([]byte, time.Duration, error)
+
+// loadCall is a load in flight, callers that miss the same key while it runs wait for its result
+type loadCall struct {
+	done  chan struct{} // closed once value and err are set
+	value []byte
+	err   error
+
+	// superseded is set when the key is written or deleted while the load runs,
+	// the loaded value is then older than what the cache holds and is not stored
+	superseded atomic.Bool
+}
+
+// loadFailure is a loader error that is returned without loading again until expiresAt
+type loadFailure struct {
+	err       error
+	expiresAt time.Time
+}
+
+// loadGroup coalesces concurrent loads of the same key into a single loader call
+type loadGroup struct {
+	mu       sync.Mutex
+	calls    map[string]*loadCall
+	failures map[string]loadFailure // only used with a negative cache TTL
+	inFlight atomic.Int64           // len(calls), lets writes skip the mutex when nothing is loading
+}
+
+func newLoadGroup() *loadGroup {
+	return &loadGroup{
+		calls:    make(map[string]*loadCall),
+		failures: make(map[string]loadFailure),
+	}
+}
+
+// join returns the load of key in flight, or a new one if there is none, in which case started is true
+// and the caller has to run it. A remembered failure is returned as a load that already finished.
+func (g *loadGroup) join(key string) (call *loadCall, started bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+
+	if failure, ok := g.failures[key]; ok {
+		if time.Now().Before(failure.expiresAt) {
+			call := &loadCall{done: make(chan struct{}), err: failure.err}
+			close(call.done)
+			return call, false
+		}
+		delete(g.failures, key)
+	}
+
+	call = &loadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.inFlight.Add(1)
+	return call, true
+}
+
+// supersede marks the load of key in flight, if any, so it does not store its value.
+// Writes and deletes call it before they take the shard lock and the load checks the mark under that lock,
+// so either the write comes after the loaded value is stored, or the loaded value is not stored at all.
+func (g *loadGroup) supersede(key string) {
+	if g.inFlight.Load() == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		call.superseded.Store(true)
+	}
+}
+
+// finish releases the callers waiting on call, a failed load is remembered for negativeTTL if it is positive
+func (g *loadGroup) finish(key string, call *loadCall, negativeTTL time.Duration) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.inFlight.Add(-1)
+	if call.err != nil && negativeTTL > 0 {
+		g.failures[key] = loadFailure{err: call.err, expiresAt: time.Now().Add(negativeTTL)}
+	}
+	g.mu.Unlock()
+
+	close(call.done)
+}
+
+// forgetExpiredFailures drops the failures whose negative cache TTL ran out,
+// so keys that are never requested again do not stay in the map
+func (g *loadGroup) forgetExpiredFailures() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for key, failure := range g.failures {
+		if !now.Before(failure.expiresAt) {
+			delete(g.failures, key)
+		}
+	}
+}
+
+// GetOrLoad returns the value of key, loading it with loader and storing it when it is missing or expired.
+// Concurrent callers that miss the same key share a single loader call and all receive its value or error.
+// With WithNegativeCacheTTL a loader error is also returned to callers arriving within that period,
+// without calling the loader again.
+//
+// The loader runs with the context of the caller that started the load, minus its cancellation,
+// so a caller that gives up does not fail the load for the others. A caller whose ctx is done
+// stops waiting and gets ctx.Err(). A loaded value is returned even if storing it fails,
+// for example because it is larger than the cache.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, loader LoaderFunc) ([]byte, error) {
+	if loader == nil {
+		return nil, ErrInvalidLoader
+	}
+
+	ctx, span := c.tracer.Start(ctx, "cache.GetOrLoad")
+	defer span.End()
+
+	// A stale value is returned right away and refreshed in the background with the same loader
+	if entry, err := c.getEntry(ctx, key, loader); err == nil {
+		endLoadSpan(span, true, false, nil)
+		return entry.Value, nil
+	}
+
+	call, started := c.loads.join(key)
+	if started {
+		go c.load(context.WithoutCancel(ctx), key, loader, call)
+	}
+
+	select {
+	case <-call.done:
+		endLoadSpan(span, false, !started, call.err)
+		return call.value, call.err
+	case <-ctx.Done():
+		endLoadSpan(span, false, !started, ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+// load runs the loader for the callers waiting on call. The value is stored before they are released,
+// so a caller that misses the key once the load is finished finds it in the cache instead of loading it again.
+// It is not stored if the key was written or deleted in the meantime, the callers still receive it.
+func (c *Cache) load(ctx context.Context, key string, loader LoaderFunc, call *loadCall) {
+	defer func() {
+		// The loader runs on its own goroutine, a panic would take down the process and leave the callers waiting
+		if r := recover(); r != nil {
+			call.value, call.err = nil, fmt.Errorf("%w: %v", ErrLoaderPanicked, r)
+		}
+		c.loads.finish(key, call, c.negativeTTL)
+	}()
+
+	start := time.Now()
+	value, ttl, err := loader(ctx)
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	// A failed write is already recorded by the metrics and the span of the Set
+	_, _ = c.set(ctx, key, value, write{ttl: ttl, loadCost: time.Since(start), condition: unlessSuperseded, load: call})
+	call.value = value
+}
@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCoalescesConcurrentMisses(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	var loads atomic.Int32
+	loader := func(context.Context) ([]byte, time.Duration, error) {
+		loads.Add(1)
+		time.Sleep(100 * time.Millisecond) // keeps the load in flight until every caller has missed
+		return []byte("value"), DefaultExpiration, nil
+	}
+
+	const callers = 50
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			value, err := cacheInstance.GetOrLoad(context.Background(), "key", loader)
+			if err != nil || string(value) != "value" {
+				t.Errorf("expected every caller to get the loaded value, got %q, %v", value, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("expected %d concurrent callers to trigger one load, got %d", callers, n)
+	}
+	if value, err := cacheInstance.Get("key"); err != nil || string(value) != "value" {
+		t.Fatalf("expected the loaded value to be stored, got %q, %v", value, err)
+	}
+}
+
+func TestGetOrLoadHitDoesNotLoad(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	cacheInstance.Set("key", []byte("cached"))
+
+	value, err := cacheInstance.GetOrLoad(context.Background(), "key", func(context.Context) ([]byte, time.Duration, error) {
+		t.Fatalf("expected a hit not to call the loader")
+		return nil, 0, nil
+	})
+	if err != nil || string(value) != "cached" {
+		t.Fatalf("expected the cached value, got %q, %v", value, err)
+	}
+}
+
+func TestGetOrLoadStoresWithLoaderTTL(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.GetOrLoad(context.Background(), "key", func(context.Context) ([]byte, time.Duration, error) {
+		return []byte("value"), 20 * time.Millisecond, nil
+	})
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := cacheInstance.Get("key"); err != ErrExpired {
+		t.Fatalf("expected the value to expire after the loader's ttl, got %v", err)
+	}
+}
+
+func TestGetOrLoadPropagatesErrorToAllWaiters(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+	errBackend := errors.New("backend unavailable")
+
+	var loads atomic.Int32
+	loader := func(context.Context) ([]byte, time.Duration, error) {
+		loads.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return nil, 0, errBackend
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cacheInstance.GetOrLoad(context.Background(), "key", loader); !errors.Is(err, errBackend) {
+				t.Errorf("expected the loader error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("expected one load, got %d", n)
+	}
+
+	// Without negative caching the next caller loads again
+	cacheInstance.GetOrLoad(context.Background(), "key", loader)
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("expected a failed load not to be remembered, got %d loads", n)
+	}
+	if _, err := cacheInstance.Get("key"); err != ErrNotFound {
+		t.Fatalf("expected nothing to be stored for a failed load, got %v", err)
+	}
+}
+
+func TestGetOrLoadNegativeCache(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithNegativeCacheTTL(50*time.Millisecond), WithMetrics(createTestMetrics(t)))
+	errBackend := errors.New("backend unavailable")
+
+	var loads atomic.Int32
+	loader := func(context.Context) ([]byte, time.Duration, error) {
+		loads.Add(1)
+		return nil, 0, errBackend
+	}
+
+	for range 3 {
+		if _, err := cacheInstance.GetOrLoad(context.Background(), "key", loader); !errors.Is(err, errBackend) {
+			t.Fatalf("expected the loader error, got %v", err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("expected the error to be remembered, got %d loads", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	cacheInstance.GetOrLoad(context.Background(), "key", loader)
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("expected the key to be loaded again after the negative cache ttl, got %d loads", n)
+	}
+}
+
+func TestGetOrLoadForgetsExpiredFailures(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithNegativeCacheTTL(10*time.Millisecond), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.GetOrLoad(context.Background(), "key", func(context.Context) ([]byte, time.Duration, error) {
+		return nil, 0, errors.New("backend unavailable")
+	})
+	time.Sleep(20 * time.Millisecond)
+	cacheInstance.sweepExpired(context.Background())
+
+	if n := len(cacheInstance.loads.failures); n != 0 {
+		t.Fatalf("expected the sweeper to drop expired failures, %d left", n)
+	}
+}
+
+func TestGetOrLoadCallerCancellation(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context) ([]byte, time.Duration, error) {
+		<-release
+		// The loader must not see the cancellation of the caller that started it
+		return []byte("value"), DefaultExpiration, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := cacheInstance.GetOrLoad(ctx, "key", loader)
+		cancelled <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	waiter := make(chan error)
+	go func() {
+		_, err := cacheInstance.GetOrLoad(context.Background(), "key", loader)
+		waiter <- err
+	}()
+
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("expected the cancelled caller to stop waiting, got %v", err)
+	}
+
+	close(release)
+	if err := <-waiter; err != nil {
+		t.Fatalf("expected the load to complete for the remaining caller, got %v", err)
+	}
+}
+
+func TestGetOrLoadDoesNotOverwriteConcurrentWrites(t *testing.T) {
+	cases := map[string]struct {
+		write    func(c *Cache)
+		expected string // empty if the key must stay missing
+	}{
+		"delete": {
+			write: func(c *Cache) {
+				c.Set("key", []byte("written"))
+				c.Delete("key")
+			},
+		},
+		"set": {
+			write:    func(c *Cache) { c.Set("key", []byte("written")) },
+			expected: "written",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+			loading, release := make(chan struct{}), make(chan struct{})
+			loaded := make(chan []byte)
+			go func() {
+				value, _ := cacheInstance.GetOrLoad(context.Background(), "key", func(context.Context) ([]byte, time.Duration, error) {
+					close(loading)
+					<-release
+					return []byte("loaded"), DefaultExpiration, nil
+				})
+				loaded <- value
+			}()
+
+			<-loading
+			tc.write(cacheInstance)
+			close(release)
+
+			// The callers still get the loaded value, it is just not stored over the newer write
+			if value := <-loaded; string(value) != "loaded" {
+				t.Fatalf("expected the caller to get the loaded value, got %q", value)
+			}
+			value, err := cacheInstance.Get("key")
+			if tc.expected == "" && err != ErrNotFound {
+				t.Fatalf("expected the deleted key to stay missing, got %q, %v", value, err)
+			}
+			if tc.expected != "" && string(value) != tc.expected {
+				t.Fatalf("expected the write during the load to be kept, got %q, %v", value, err)
+			}
+		})
+	}
+}
+
+func TestGetOrLoadLoaderPanic(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	_, err := cacheInstance.GetOrLoad(context.Background(), "key", func(context.Context) ([]byte, time.Duration, error) {
+		panic("boom")
+	})
+	if !errors.Is(err, ErrLoaderPanicked) {
+		t.Fatalf("expected ErrLoaderPanicked, got %v", err)
+	}
+
+	if _, err := cacheInstance.GetOrLoad(context.Background(), "key", nil); err != ErrInvalidLoader {
+		t.Fatalf("expected ErrInvalidLoader for a nil loader, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cache-service/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Attribute sets are built once, so recording a measurement on the hot path does not allocate
+var (
+	getHitAttrs   = metric.WithAttributeSet(attribute.NewSet(telemetry.OperationKey.String("get"), telemetry.ResultKey.String("hit")))
+	getMissAttrs  = metric.WithAttributeSet(attribute.NewSet(telemetry.OperationKey.String("get"), telemetry.ResultKey.String("miss")))
+	setOkAttrs    = metric.WithAttributeSet(attribute.NewSet(telemetry.OperationKey.String("set"), telemetry.ResultKey.String("ok")))
+	setErrorAttrs = metric.WithAttributeSet(attribute.NewSet(telemetry.OperationKey.String("set"), telemetry.ResultKey.String("error")))
+
+	setConditionFailedAttrs = metric.WithAttributeSet(attribute.NewSet(telemetry.OperationKey.String("set"), telemetry.ResultKey.String("condition_failed")))
+
+	evictedCapacityAttrs = metric.WithAttributeSet(attribute.NewSet(telemetry.ReasonKey.String(EvictedCapacity.String())))
+	evictedExpiredAttrs  = metric.WithAttributeSet(attribute.NewSet(telemetry.ReasonKey.String(EvictedExpired.String())))
+
+	cacheFullAttrs     = metric.WithAttributeSet(attribute.NewSet(telemetry.ErrorKey.String("cache_full")))
+	valueTooLargeAttrs = metric.WithAttributeSet(attribute.NewSet(telemetry.ErrorKey.String("value_too_large")))
+	tooManyKeysAttrs   = metric.WithAttributeSet(attribute.NewSet(telemetry.ErrorKey.String("too_many_keys")))
+)
+
+// recordGetLatency records how long a Get took, expired items count as a miss
+func recordGetLatency(ctx context.Context, metrics *telemetry.CacheMetrics, start time.Time, err error) {
+	attrs := getHitAttrs
+	if err != nil {
+		attrs = getMissAttrs
+	}
+	metrics.Latency.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// recordSetLatency records how long a Set took, a conditional write that was not applied is not an error
+func recordSetLatency(ctx context.Context, metrics *telemetry.CacheMetrics, start time.Time, err error) {
+	attrs := setOkAttrs
+	switch {
+	case isConditionFailure(err):
+		attrs = setConditionFailedAttrs
+	case err != nil:
+		attrs = setErrorAttrs
+	}
+	metrics.Latency.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// recordEviction counts a key the cache removed on its own, either to make space or because it expired
+func recordEviction(ctx context.Context, metrics *telemetry.CacheMetrics, reason EvictionReason) {
+	attrs := evictedCapacityAttrs
+	if reason == EvictedExpired {
+		attrs = evictedExpiredAttrs
+	}
+	metrics.Evictions.Add(ctx, 1, attrs)
+}
+
+// recordError counts a write that failed because the shard ran out of room
+func recordError(ctx context.Context, metrics *telemetry.CacheMetrics, err error) {
+	switch {
+	case errors.Is(err, ErrCacheFull):
+		metrics.ErrorCount.Add(ctx, 1, cacheFullAttrs)
+	case errors.Is(err, ErrValueTooLarge):
+		metrics.ErrorCount.Add(ctx, 1, valueTooLargeAttrs)
+	case errors.Is(err, ErrTooManyKeys):
+		metrics.ErrorCount.Add(ctx, 1, tooManyKeysAttrs)
+	}
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cache-service/internal/evictors"
+	"cache-service/internal/telemetry"
+)
+
+func TestCacheRecordsEvictions(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(4), WithMetrics(metrics))
+
+	cacheInstance.Set("a", []byte("1111"))
+	cacheInstance.Set("b", []byte("2"))
+	cacheInstance.SetWithTTL("c", []byte("3"), time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+	cacheInstance.Get("c")
+
+	if n := collectSum(t, reader, "cache_evictions", telemetry.ReasonKey.String("capacity")); n != 1 {
+		t.Fatalf("expected 1 capacity eviction, got %d", n)
+	}
+	if n := collectSum(t, reader, "cache_evictions", telemetry.ReasonKey.String("expired")); n != 1 {
+		t.Fatalf("expected 1 expired eviction, got %d", n)
+	}
+}
+
+func TestCacheRecordsErrors(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(4), WithMaxKeys(1), WithMetrics(metrics))
+
+	cacheInstance.Set("a", []byte("12345"))
+	cacheInstance.Set("a", []byte("1"))
+	cacheInstance.Set("b", []byte("2"))
+
+	if n := collectSum(t, reader, "cache_error_count", telemetry.ErrorKey.String("value_too_large")); n != 1 {
+		t.Fatalf("expected 1 value_too_large error, got %d", n)
+	}
+	if n := collectSum(t, reader, "cache_error_count", telemetry.ErrorKey.String("too_many_keys")); n != 1 {
+		t.Fatalf("expected 1 too_many_keys error, got %d", n)
+	}
+}
+
+func TestCacheRecordsCacheFullError(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	factory := func() evictors.Evictor { return testEvictor{} }
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(2), WithEvictorFactory(factory), WithMetrics(metrics))
+
+	cacheInstance.Set("b", []byte("bb"))
+
+	// testEvictor only ever offers a key that is not in the shard, so no space can be made
+	if err := cacheInstance.Set("c", []byte("c")); err != ErrCacheFull {
+		t.Fatalf("expected ErrCacheFull, got %v", err)
+	}
+	if n := collectSum(t, reader, "cache_error_count", telemetry.ErrorKey.String("cache_full")); n != 1 {
+		t.Fatalf("expected 1 cache_full error, got %d", n)
+	}
+}
+
+func TestCacheRecordsLatency(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMetrics(metrics))
+
+	cacheInstance.Set("a", []byte("1"))
+	cacheInstance.Set("b", nil)
+	cacheInstance.SetIfAbsent("a", []byte("2"))
+	cacheInstance.CompareAndSet("a", []byte("2"), 0)
+	cacheInstance.Get("a")
+	cacheInstance.Get("a")
+	cacheInstance.Get("missing")
+
+	cases := []struct {
+		operation, result string
+		expected          uint64
+	}{
+		{"set", "ok", 1},
+		{"set", "error", 1},
+		{"set", "condition_failed", 2},
+		{"get", "hit", 2},
+		{"get", "miss", 1},
+	}
+
+	for _, tc := range cases {
+		n := collectHistogramCount(t, reader, "cache_operation_latency", telemetry.OperationKey.String(tc.operation), telemetry.ResultKey.String(tc.result))
+		if n != tc.expected {
+			t.Fatalf("expected %d %s/%s measurements, got %d", tc.expected, tc.operation, tc.result, n)
+		}
+	}
+}
+
+func TestCacheReportsUsageGauges(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(2), WithMaxSize(100), WithMaxKeys(10), WithMetrics(metrics))
+
+	cacheInstance.Set("a", []byte("1234"))
+	cacheInstance.Set("b", []byte("12"))
+	cacheInstance.Set("c", []byte("1"))
+	cacheInstance.Set("c", []byte("123"))
+	cacheInstance.Delete("b")
+
+	if n := collectGauge(t, reader, "cache_item_count"); n != 2 {
+		t.Fatalf("expected 2 items, got %d", n)
+	}
+	if n := collectGauge(t, reader, "cache_size_bytes"); n != 7 {
+		t.Fatalf("expected 7 bytes in use, got %d", n)
+	}
+	if n := collectGauge(t, reader, "cache_max_size_bytes"); n != 100 {
+		t.Fatalf("expected a capacity of 100 bytes, got %d", n)
+	}
+	if n := collectGauge(t, reader, "cache_max_keys"); n != 10 {
+		t.Fatalf("expected a capacity of 10 keys, got %d", n)
+	}
+}
+
+func TestCacheReportsConfiguredCapacityWithMoreShardsThanKeys(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	NewCache(context.Background(), WithShardCount(256), WithMaxSize(1000), WithMaxKeys(10), WithMetrics(metrics))
+
+	// Each shard's fair share rounds, the gauges report what was configured
+	if n := collectGauge(t, reader, "cache_max_size_bytes"); n != 1000 {
+		t.Fatalf("expected a capacity of 1000 bytes, got %d", n)
+	}
+	if n := collectGauge(t, reader, "cache_max_keys"); n != 10 {
+		t.Fatalf("expected a capacity of 10 keys, got %d", n)
+	}
+}
+
+func TestCacheStopsReportingAfterContextIsCancelled(t *testing.T) {
+	metrics, reader := createRecordedTestMetrics(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cacheInstance, _ := NewCache(ctx, WithShardCount(1), WithMetrics(metrics))
+
+	cacheInstance.Set("a", []byte("1"))
+	cancel()
+
+	// The registration is removed by a function that runs after the cancellation
+	deadline := time.Now().Add(time.Second)
+	for collectGauge(t, reader, "cache_item_count") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the gauges to stop reporting the cancelled cache")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"cache-service/internal/telemetry"
 	"fmt"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CacheOption func(*Cache) error
@@ -19,6 +21,17 @@ func WithTTL(ttl time.Duration) CacheOption {
 	}
 }
 
+// WithExpirySweepInterval sets how often the background sweeper removes expired items.
+func WithExpirySweepInterval(interval time.Duration) CacheOption {
+	return func(c *Cache) error {
+		if interval <= 0 {
+			return fmt.Errorf("expiry sweep interval must be positive, got %v", interval)
+		}
+		c.sweepInterval = interval
+		return nil
+	}
+}
+
 func WithMaxSize(maxSize int64) CacheOption {
 	return func(c *Cache) error {
 		if maxSize <= 0 {
@@ -29,6 +42,19 @@ func WithMaxSize(maxSize int64) CacheOption {
 	}
 }
 
+// WithSizeAccounting sets what an entry is charged against the max size.
+// With AccountHeap the max size bounds the estimated heap of the cache rather than the bytes of the values,
+// which keeps many small entries from using several times the max size.
+func WithSizeAccounting(accounting SizeAccounting) CacheOption {
+	return func(c *Cache) error {
+		if accounting != AccountPayload && accounting != AccountHeap {
+			return fmt.Errorf("unknown size accounting %d", accounting)
+		}
+		c.accounting = accounting
+		return nil
+	}
+}
+
 func WithMaxKeys(maxKeys int) CacheOption {
 	return func(c *Cache) error {
 		if maxKeys <= 0 {
@@ -68,3 +94,80 @@ func WithMetrics(metrics *telemetry.CacheMetrics) CacheOption {
 		return nil
 	}
 }
+
+// WithOnEvict registers a callback that is called for every key that leaves the cache,
+// with the value it held and the reason it was removed.
+// The callback runs on the goroutine that removed the key, after the shard lock is released,
+// so it may call back into the cache but should not block for long.
+func WithOnEvict(onEvict EvictionFunc) CacheOption {
+	return func(c *Cache) error {
+		if onEvict == nil {
+			return fmt.Errorf("eviction callback must not be nil")
+		}
+		c.onEvict = onEvict
+		return nil
+	}
+}
+
+// WithNegativeCacheTTL makes GetOrLoad remember a loader error for ttl,
+// callers asking for the key within that period get the error without the loader being called again.
+func WithNegativeCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) error {
+		if ttl <= 0 {
+			return fmt.Errorf("negative cache ttl must be positive, got %v", ttl)
+		}
+		c.negativeTTL = ttl
+		return nil
+	}
+}
+
+// WithStaleWhileRevalidate keeps serving a value for window after its TTL ran out.
+// GetWithMeta reports such a value as stale, and reading it starts one refresh in the background
+// through the loader set with WithRefreshLoader, or the loader passed to GetOrLoad.
+// The value is removed once the window has passed too.
+func WithStaleWhileRevalidate(window time.Duration) CacheOption {
+	return func(c *Cache) error {
+		if window <= 0 {
+			return fmt.Errorf("stale window must be positive, got %v", window)
+		}
+		c.staleFor = window
+		return nil
+	}
+}
+
+// WithRefreshLoader sets the loader that refreshes stale values and values due for an early refresh.
+// Refreshes of the same key are coalesced with the loads of GetOrLoad.
+func WithRefreshLoader(refresh RefreshFunc) CacheOption {
+	return func(c *Cache) error {
+		if refresh == nil {
+			return fmt.Errorf("refresh loader must not be nil")
+		}
+		c.refresh = refresh
+		return nil
+	}
+}
+
+// WithEarlyRefresh refreshes values loaded through the cache shortly before their TTL runs out,
+// using probabilistic early expiration (XFetch). The closer a value is to its TTL and the longer it took to load,
+// the more likely a read refreshes it. beta scales how early that happens, 1 is a good default.
+func WithEarlyRefresh(beta float64) CacheOption {
+	return func(c *Cache) error {
+		if beta <= 0 {
+			return fmt.Errorf("early refresh beta must be positive, got %v", beta)
+		}
+		c.earlyRefresh = beta
+		return nil
+	}
+}
+
+// WithTracerProvider creates the spans of GetContext, SetWithTTLContext and GetOrLoad with the given provider.
+// Without it spans are not recorded.
+func WithTracerProvider(provider trace.TracerProvider) CacheOption {
+	return func(c *Cache) error {
+		if provider == nil {
+			return fmt.Errorf("tracer provider must not be nil")
+		}
+		c.tracer = provider.Tracer(tracerName)
+		return nil
+	}
+}
@@ -1,11 +1,14 @@
 package cache
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"cache-service/internal/evictors"
 	"cache-service/internal/telemetry"
+
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const (
@@ -25,6 +28,19 @@ func TestWithTTL(t *testing.T) {
 	}
 }
 
+func TestWithExpirySweepInterval(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithExpirySweepInterval(time.Second)(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.sweepInterval != time.Second {
+		t.Fatalf("expected sweepInterval to be set")
+	}
+	if err := WithExpirySweepInterval(0)(cacheInstance); err == nil {
+		t.Fatalf("expected error for interval <= 0")
+	}
+}
+
 func TestWithMaxSize(t *testing.T) {
 	cacheInstance := &Cache{}
 	if err := WithMaxSize(1024)(cacheInstance); err != nil {
@@ -94,3 +110,95 @@ func TestWithMetrics(t *testing.T) {
 		t.Fatalf("expected error for nil metrics")
 	}
 }
+
+func TestWithOnEvict(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithOnEvict(func(string, []byte, EvictionReason) {})(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.onEvict == nil {
+		t.Fatalf("expected onEvict to be set")
+	}
+	if err := WithOnEvict(nil)(cacheInstance); err == nil {
+		t.Fatalf("expected error for nil callback")
+	}
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithTracerProvider(noop.NewTracerProvider())(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.tracer == nil {
+		t.Fatalf("expected tracer to be set")
+	}
+	if err := WithTracerProvider(nil)(cacheInstance); err == nil {
+		t.Fatalf("expected error for nil tracer provider")
+	}
+}
+
+func TestWithSizeAccounting(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithSizeAccounting(AccountHeap)(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.accounting != AccountHeap {
+		t.Fatalf("expected heap accounting, got %s", cacheInstance.accounting)
+	}
+	if err := WithSizeAccounting(SizeAccounting(7))(cacheInstance); err == nil {
+		t.Fatalf("expected error for unknown size accounting")
+	}
+}
+
+func TestWithNegativeCacheTTL(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithNegativeCacheTTL(time.Second)(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.negativeTTL != time.Second {
+		t.Fatalf("expected negative cache ttl to be set")
+	}
+	if err := WithNegativeCacheTTL(0)(cacheInstance); err == nil {
+		t.Fatalf("expected error for zero negative cache ttl")
+	}
+}
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithStaleWhileRevalidate(time.Minute)(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.staleFor != time.Minute {
+		t.Fatalf("expected stale window to be set")
+	}
+	if err := WithStaleWhileRevalidate(0)(cacheInstance); err == nil {
+		t.Fatalf("expected error for zero stale window")
+	}
+}
+
+func TestWithRefreshLoader(t *testing.T) {
+	cacheInstance := &Cache{}
+	refresh := func(context.Context, string) ([]byte, time.Duration, error) { return nil, 0, nil }
+	if err := WithRefreshLoader(refresh)(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.refresh == nil {
+		t.Fatalf("expected refresh loader to be set")
+	}
+	if err := WithRefreshLoader(nil)(cacheInstance); err == nil {
+		t.Fatalf("expected error for nil refresh loader")
+	}
+}
+
+func TestWithEarlyRefresh(t *testing.T) {
+	cacheInstance := &Cache{}
+	if err := WithEarlyRefresh(1)(cacheInstance); err != nil {
+		t.Fatalf(unexpectedErrStr, err)
+	}
+	if cacheInstance.earlyRefresh != 1 {
+		t.Fatalf("expected early refresh beta to be set")
+	}
+	if err := WithEarlyRefresh(0)(cacheInstance); err == nil {
+		t.Fatalf("expected error for zero beta")
+	}
+}
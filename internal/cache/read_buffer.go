@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+const (
+	// readStripeCount spreads concurrent readers of a shard over independent stripes
+	readStripeCount = 16
+
+	// readBufferSize is the number of reads a stripe collects before they are handed to the evictor
+	readBufferSize = 64
+)
+
+// readBuffer batches recency updates from the read path, so a hit does not have to
+// take the evictor's lock. This follows the read buffers used by Caffeine:
+// reads are appended to one of several stripes picked by the key's hash and
+// a full stripe is drained into the evictor in one go.
+//
+// The buffer is lossy by design. A read is dropped when its stripe is busy,
+// and a batch is skipped when another drain is already running. Losing a few recency updates
+// only makes the eviction policy slightly less precise, it never affects correctness.
+type readBuffer struct {
+	seed    maphash.Seed
+	stripes [readStripeCount]readStripe
+	drainMu sync.Mutex
+	drain   func(keys []string)
+}
+
+type readStripe struct {
+	mu   sync.Mutex
+	keys []string
+	_    [40]byte // keeps neighbouring stripes on separate cache lines
+}
+
+func newReadBuffer(drain func(keys []string)) *readBuffer {
+	return &readBuffer{
+		seed:  maphash.MakeSeed(),
+		drain: drain,
+	}
+}
+
+// record adds a read of key to the buffer and drains the stripe once it is full.
+// It never blocks, if the stripe is in use by another reader the read is dropped.
+func (rb *readBuffer) record(key string) {
+	stripe := &rb.stripes[maphash.String(rb.seed, key)%readStripeCount]
+	if !stripe.mu.TryLock() {
+		return
+	}
+	defer stripe.mu.Unlock()
+
+	if stripe.keys == nil {
+		stripe.keys = make([]string, 0, readBufferSize)
+	}
+	stripe.keys = append(stripe.keys, key)
+
+	if len(stripe.keys) < readBufferSize {
+		return
+	}
+
+	// Only one batch is applied at a time, other full stripes are dropped instead of waiting
+	if rb.drainMu.TryLock() {
+		rb.drain(stripe.keys)
+		rb.drainMu.Unlock()
+	}
+	clear(stripe.keys)
+	stripe.keys = stripe.keys[:0]
+}
+
+// flush drains the reads buffered in every stripe, however few there are.
+// It is called before the evictor picks a victim, so recent hits count even if no stripe filled up.
+// Unlike record it waits for stripes in use, readers never wait on drainMu so this cannot deadlock.
+func (rb *readBuffer) flush() {
+	rb.drainMu.Lock()
+	defer rb.drainMu.Unlock()
+
+	for i := range rb.stripes {
+		stripe := &rb.stripes[i]
+		stripe.mu.Lock()
+		if len(stripe.keys) > 0 {
+			rb.drain(stripe.keys)
+			clear(stripe.keys)
+			stripe.keys = stripe.keys[:0]
+		}
+		stripe.mu.Unlock()
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReadBufferDrainsFullStripe(t *testing.T) {
+	var drained []string
+	rb := newReadBuffer(func(keys []string) {
+		drained = append(drained, keys...)
+	})
+
+	// All reads of the same key land on the same stripe
+	for range readBufferSize - 1 {
+		rb.record("a")
+	}
+	if len(drained) != 0 {
+		t.Fatalf("expected no drain before the stripe is full, got %d keys", len(drained))
+	}
+
+	rb.record("a")
+	if len(drained) != readBufferSize {
+		t.Fatalf("expected %d keys to be drained, got %d", readBufferSize, len(drained))
+	}
+}
+
+func TestReadBufferFlushDrainsPartialStripes(t *testing.T) {
+	var drained []string
+	rb := newReadBuffer(func(keys []string) {
+		drained = append(drained, keys...)
+	})
+
+	rb.record("a")
+	rb.record("b")
+	rb.record("a")
+	rb.flush()
+	if len(drained) != 3 {
+		t.Fatalf("expected flush to drain every buffered read, got %v", drained)
+	}
+
+	rb.flush()
+	if len(drained) != 3 {
+		t.Fatalf("expected flushed reads not to be drained twice, got %v", drained)
+	}
+}
+
+func TestReadBufferConcurrentRecord(t *testing.T) {
+	var mu sync.Mutex
+	drained := 0
+	rb := newReadBuffer(func(keys []string) {
+		mu.Lock()
+		drained += len(keys)
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys := []string{"a", "b", "c", "d"}
+			for i := range 10_000 {
+				rb.record(keys[(g+i)%len(keys)])
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Reads may be dropped under contention, but whatever is drained comes in full batches
+	if drained%readBufferSize != 0 {
+		t.Fatalf("expected drained reads to be a multiple of %d, got %d", readBufferSize, drained)
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RefreshFunc loads the current value of key to replace a stale one, see WithRefreshLoader.
+// It returns the TTL to store the value with like LoaderFunc.
+type RefreshFunc func(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+// refreshEarly implements probabilistic early expiration (XFetch, Vattani et al.).
+// A fresh value is refreshed with a probability that grows as its TTL runs out and with how long it took to load,
+// so the refreshes of a hot key are spread over the time before it expires instead of all callers missing at once.
+func (c *Cache) refreshEarly(item *cacheItem, now time.Time) bool {
+	if c.earlyRefresh <= 0 || item.loadCost <= 0 || item.FreshUntil.IsZero() {
+		return false
+	}
+
+	// -log of a uniform number in (0, 1] is exponentially distributed, 1 - rand.Float64() keeps log(0) out
+	gap := time.Duration(float64(item.loadCost) * c.earlyRefresh * -math.Log(1-rand.Float64()))
+	return !now.Add(gap).Before(item.FreshUntil)
+}
+
+// refreshInBackground loads the key again unless a load is already in flight or its last failure is remembered.
+// Returns whether a load was started, which it is not without a loader.
+func (c *Cache) refreshInBackground(ctx context.Context, key string, loader LoaderFunc) bool {
+	if loader == nil {
+		if c.refresh == nil {
+			return false
+		}
+		loader = func(ctx context.Context) ([]byte, time.Duration, error) { return c.refresh(ctx, key) }
+	}
+
+	call, started := c.loads.join(key)
+	if started {
+		go c.load(context.WithoutCancel(ctx), key, loader, call)
+	}
+	return started
+}
@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetWithMetaServesStaleValueAndRefreshesOnce(t *testing.T) {
+	var refreshes atomic.Int32
+	release := make(chan struct{})
+	refresh := func(_ context.Context, key string) ([]byte, time.Duration, error) {
+		refreshes.Add(1)
+		<-release
+		return []byte("new"), time.Minute, nil
+	}
+	cacheInstance, _ := NewCache(context.Background(),
+		WithStaleWhileRevalidate(time.Minute),
+		WithRefreshLoader(refresh),
+		WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.SetWithTTL("key", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry, err := cacheInstance.GetWithMeta(context.Background(), "key")
+			if err != nil || !entry.Stale || string(entry.Value) != "old" {
+				t.Errorf("expected the stale value, got %q stale=%v, %v", entry.Value, entry.Stale, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	waitFor(t, func() bool {
+		entry, _ := cacheInstance.GetWithMeta(context.Background(), "key")
+		return !entry.Stale && string(entry.Value) == "new"
+	})
+	if n := refreshes.Load(); n != 1 {
+		t.Fatalf("expected the stale reads to trigger one refresh, got %d", n)
+	}
+}
+
+func TestGetWithMetaStaleWithoutRefreshLoader(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithStaleWhileRevalidate(30*time.Millisecond), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.SetWithTTL("key", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if value, err := cacheInstance.Get("key"); err != nil || string(value) != "old" {
+		t.Fatalf("expected Get to return the stale value, got %q, %v", value, err)
+	}
+
+	// Past the stale window the value is gone
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cacheInstance.GetWithMeta(context.Background(), "key"); err != ErrExpired {
+		t.Fatalf("expected ErrExpired after the stale window, got %v", err)
+	}
+}
+
+func TestGetWithMetaNeverStaleWithoutWindow(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.SetWithTTL("key", []byte("v"), 10*time.Millisecond)
+	if entry, _ := cacheInstance.GetWithMeta(context.Background(), "key"); entry.Stale {
+		t.Fatalf("expected a fresh value")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, err := cacheInstance.GetWithMeta(context.Background(), "key"); err != ErrExpired {
+		t.Fatalf("expected ErrExpired without a stale window, got %v", err)
+	}
+}
+
+func TestSetWithStaleWindow(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithStaleWhileRevalidate(time.Minute), WithMetrics(createTestMetrics(t)))
+
+	// A key with its own window is removed once that window has passed, the others follow the cache wide window
+	cacheInstance.SetWithStaleWindow("short", []byte("v"), 10*time.Millisecond, 20*time.Millisecond)
+	cacheInstance.SetWithStaleWindow("default", []byte("v"), 10*time.Millisecond, DefaultExpiration)
+	cacheInstance.SetWithStaleWindow("forever", []byte("v"), 10*time.Millisecond, NoExpiration)
+	time.Sleep(20 * time.Millisecond)
+	if entry, err := cacheInstance.GetWithMeta(context.Background(), "short"); err != nil || !entry.Stale {
+		t.Fatalf("expected the stale value within its window, got stale=%v, %v", entry.Stale, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, err := cacheInstance.GetWithMeta(context.Background(), "short"); err != ErrExpired {
+		t.Fatalf("expected ErrExpired after the window of the key, got %v", err)
+	}
+	for _, key := range []string{"default", "forever"} {
+		if entry, err := cacheInstance.GetWithMeta(context.Background(), key); err != nil || !entry.Stale {
+			t.Fatalf("expected %s to still be served stale, got stale=%v, %v", key, entry.Stale, err)
+		}
+	}
+
+	if _, err := cacheInstance.SetWithStaleWindow("key", []byte("v"), time.Minute, -2*time.Second); err != ErrInvalidTTL {
+		t.Fatalf("expected ErrInvalidTTL, got %v", err)
+	}
+}
+
+func TestGetOrLoadRefreshesStaleValueInBackground(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithStaleWhileRevalidate(time.Minute), WithMetrics(createTestMetrics(t)))
+	cacheInstance.SetWithTTL("key", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	value, err := cacheInstance.GetOrLoad(context.Background(), "key", func(context.Context) ([]byte, time.Duration, error) {
+		return []byte("new"), time.Minute, nil
+	})
+	if err != nil || string(value) != "old" {
+		t.Fatalf("expected the stale value to be returned right away, got %q, %v", value, err)
+	}
+
+	waitFor(t, func() bool {
+		value, _ := cacheInstance.Get("key")
+		return string(value) == "new"
+	})
+}
+
+func TestFailedRefreshKeepsStaleValue(t *testing.T) {
+	var refreshes atomic.Int32
+	refresh := func(context.Context, string) ([]byte, time.Duration, error) {
+		refreshes.Add(1)
+		return nil, 0, errors.New("backend unavailable")
+	}
+	cacheInstance, _ := NewCache(context.Background(),
+		WithStaleWhileRevalidate(time.Minute),
+		WithRefreshLoader(refresh),
+		WithNegativeCacheTTL(time.Minute),
+		WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.SetWithTTL("key", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	cacheInstance.Get("key")
+	waitFor(t, func() bool { return refreshes.Load() == 1 })
+
+	// The failure is remembered, so further stale reads do not hammer the backend
+	for range 10 {
+		if value, err := cacheInstance.Get("key"); err != nil || string(value) != "old" {
+			t.Fatalf("expected the stale value to survive a failed refresh, got %q, %v", value, err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := refreshes.Load(); n != 1 {
+		t.Fatalf("expected one refresh, got %d", n)
+	}
+}
+
+func TestRefreshEarly(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithEarlyRefresh(1), WithMetrics(createTestMetrics(t)))
+	now := time.Now()
+
+	due := &cacheItem{FreshUntil: now, loadCost: time.Second}
+	if !cacheInstance.refreshEarly(due, now) {
+		t.Fatalf("expected a value at the end of its ttl to be refreshed")
+	}
+
+	notDue := &cacheItem{FreshUntil: now.Add(time.Hour), loadCost: time.Millisecond}
+	notLoaded := &cacheItem{FreshUntil: now}
+	for range 1000 {
+		if cacheInstance.refreshEarly(notDue, now) {
+			t.Fatalf("expected a value an hour from expiry that loads in 1ms not to be refreshed")
+		}
+		if cacheInstance.refreshEarly(notLoaded, now) {
+			t.Fatalf("expected a value that was not loaded through the cache not to be refreshed early")
+		}
+	}
+
+	// Half a load cost before expiry, XFetch refreshes with probability e^-0.5, about 60%
+	halfway := &cacheItem{FreshUntil: now.Add(500 * time.Millisecond), loadCost: time.Second}
+	refreshed := 0
+	for range 10_000 {
+		if cacheInstance.refreshEarly(halfway, now) {
+			refreshed++
+		}
+	}
+	if refreshed < 5000 || refreshed > 7000 {
+		t.Fatalf("expected about 60%% of reads to refresh, got %d of 10000", refreshed)
+	}
+}
+
+func TestEarlyRefreshReloadsBeforeExpiry(t *testing.T) {
+	var refreshes atomic.Int32
+	refresh := func(context.Context, string) ([]byte, time.Duration, error) {
+		refreshes.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return []byte("v"), 100 * time.Millisecond, nil
+	}
+	cacheInstance, _ := NewCache(context.Background(), WithRefreshLoader(refresh), WithEarlyRefresh(3), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, time.Duration, error) {
+		return refresh(ctx, "key")
+	})
+
+	// Keep reading for a few TTLs, the value has to be refreshed before it expires every time
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if _, err := cacheInstance.Get("key"); err != nil {
+			t.Fatalf("expected early refreshes to keep the key cached, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if refreshes.Load() < 2 {
+		t.Fatalf("expected the value to be refreshed early, got %d loads", refreshes.Load())
+	}
+}
+
+// waitFor polls condition until it holds or a second has passed
+func waitFor(tb testing.TB, condition func() bool) {
+	tb.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			tb.Fatalf("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -3,7 +3,9 @@ package cache
 import (
 	"context"
 	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cache-service/internal/evictors"
@@ -15,18 +17,60 @@ type cacheShard struct {
 	items       map[string]*cacheItem
 	mu          sync.RWMutex
 	ttl         time.Duration
-	maxSize     int64
-	maxKeys     int
-	currentSize int64
+	staleFor    time.Duration // how long a value is served stale after its ttl ran out
+	version     uint64        // version of the last write, see cacheItem.Version
+	maxSize     int64         // the shard's fair share of the budget, it may hold more while other shards have room
+	maxKeys     int           // fair share of the keys in the budget
+	budget      *budget       // capacity shared with the other shards of the cache
+	accounting  SizeAccounting
+	overhead    int64 // heap used per entry besides its key and value, charged with AccountHeap
+	currentSize int64 // bytes charged for the items, see itemSize
+	expiries    expiryHeap
 	evictor     evictors.Evictor
+	reads       *readBuffer             // batches recency updates from get
+	directReads bool                    // the evictor records hits without a lock, so they skip the read buffer
+	onEvict     EvictionFunc            // optional, called after the lock is released
+	evicted     []evictedEntry          // entries removed under the lock that onEvict has not seen yet
+	counters    shardCounters           // reported through Cache.Stats
 	metrics     *telemetry.CacheMetrics // for tracking metrics at shard level
 	ctx         context.Context
 }
 
+// shardCounters count what happened to a shard since it was created,
+// they are updated without the shard lock so reads can update them concurrently
+type shardCounters struct {
+	hits        atomic.Int64
+	misses      atomic.Int64
+	evictions   atomic.Int64
+	expirations atomic.Int64
+}
+
 type cacheItem struct {
-	Value     []byte
-	ExpiresAt time.Time
-	Size      int64
+	Key        string
+	Value      []byte
+	FreshUntil time.Time     // soft expiry, the value is stale from then on until ExpiresAt
+	ExpiresAt  time.Time     // hard expiry, the item is removed
+	Size       int64         // bytes charged against the budget
+	Version    uint64        // increases with every write to the shard, so a key never gets a version twice
+	loadCost   time.Duration // how long loading the value took, zero if it was not loaded through the cache
+	heapIndex  int           // position in the shard's expiry heap
+}
+
+// isStale reports whether the value outlived its ttl and is only served until a refresh replaces it
+func (c *cacheItem) isStale(now time.Time) bool {
+	return !c.FreshUntil.IsZero() && now.After(c.FreshUntil)
+}
+
+// write describes how put stores a value
+type write struct {
+	ttl       time.Duration
+	staleFor  time.Duration // how long the value is served stale after ttl, DefaultExpiration for the cache wide window
+	loadCost  time.Duration // how long the value took to load, zero if it was not loaded through the cache
+	condition writeCondition
+	version   uint64 // the version expected by ifVersion
+	increment bool   // the value is the stored integer plus delta, see IncrBy
+	delta     int64
+	load      *loadCall // the load that produced the value, checked by unlessSuperseded
 }
 
 func (c *cacheItem) isExpired() bool {
@@ -60,108 +104,320 @@ func newShard(ctx context.Context, shardId string, ttl time.Duration, maxSize in
 	}
 
 	c := &cacheShard{
-		id:      shardId,
-		items:   make(map[string]*cacheItem),
-		ttl:     ttl,
-		maxSize: maxSize,
-		maxKeys: maxKeys,
-		evictor: evictorInstance,
-		metrics: metrics,
-		ctx:     ctx,
+		id:       shardId,
+		items:    make(map[string]*cacheItem),
+		ttl:      ttl,
+		maxSize:  maxSize,
+		maxKeys:  maxKeys,
+		budget:   newBudget(maxSize, maxKeys),
+		evictor:  evictorInstance,
+		overhead: entryOverhead(evictorInstance),
+		metrics:  metrics,
+		ctx:      ctx,
+	}
+
+	c.reads = newReadBuffer(c.applyReads)
+	_, c.directReads = evictorInstance.(evictors.LockFreeReader)
+
+	if capacityAware, ok := evictorInstance.(evictors.CapacityAware); ok {
+		capacityAware.SetCapacity(maxKeys)
 	}
 
 	return c, nil
 }
 
 func (c *cacheShard) set(key string, value []byte) error {
-	incomingItemSize := int64(len(value))
-	if incomingItemSize == 0 {
-		return ErrInvalidValue
+	return c.setWithTTL(key, value, DefaultExpiration)
+}
+
+func (c *cacheShard) setWithTTL(key string, value []byte, ttl time.Duration) error {
+	_, _, err := c.put(key, value, write{ttl: ttl})
+	return err
+}
+
+// put stores the value as described by w and returns how many items were removed to make space for it
+// and the item it stored. A write whose condition does not hold fails without changing anything.
+// An increment ignores value and stores the result of adding w.delta to the stored integer instead.
+//
+// When the budget is full the shard only evicts its own items if the write takes it past its fair share.
+// Otherwise the space is held by other shards and put returns ErrCacheFull without evicting anything,
+// the cache then reclaims space from the largest shards and retries.
+func (c *cacheShard) put(key string, value []byte, w write) (int, *cacheItem, error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	current, exists := c.items[key]
+	if exists && current.isExpired() {
+		current = nil
+	}
+	if err := w.check(current); err != nil {
+		return 0, nil, err
 	}
 
-	// Check if the item exceeds the maximum size of the entire shard
-	if c.maxSize > 0 && incomingItemSize > c.maxSize {
-		c.metrics.ErrorCount.Add(c.ctx, 1)
-		return ErrValueTooLarge
+	freshUntil := c.expiresAt(w.ttl)
+	expiresAt := c.staleUntil(freshUntil, w.staleFor)
+	if w.increment {
+		var err error
+		// A stale counter is only kept to be served while it is refreshed, it starts over like an expired one
+		if current != nil && current.isStale(time.Now()) {
+			current = nil
+		}
+		if value, err = incremented(current, w.delta); err != nil {
+			return 0, nil, err
+		}
+		// A counter keeps the expiry it was created with, like INCR in Redis
+		if current != nil {
+			freshUntil, expiresAt = current.FreshUntil, current.ExpiresAt
+		}
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if len(value) == 0 {
+		return 0, nil, ErrInvalidValue
+	}
+	incomingItemSize := c.itemSize(key, len(value))
 
-	_, exists := c.items[key]
-	if !exists && (c.maxKeys > 0 && len(c.items)+1 > c.maxKeys) {
-		c.metrics.ErrorCount.Add(c.ctx, 1)
-		return ErrTooManyKeys
+	// Check if the item exceeds the capacity of the entire cache
+	if incomingItemSize > c.budget.maxBytes {
+		recordError(c.ctx, c.metrics, ErrValueTooLarge)
+		return 0, nil, ErrValueTooLarge
 	}
 
-	extraSpaceNeeded := incomingItemSize
+	if !exists && !c.budget.reserveKey() {
+		recordError(c.ctx, c.metrics, ErrTooManyKeys)
+		return 0, nil, ErrTooManyKeys
+	}
+
+	var oldSize int64
 	if exists {
-		extraSpaceNeeded -= c.items[key].Size
+		oldSize = c.items[key].Size
+	}
+	extraSpaceNeeded := incomingItemSize - oldSize
+
+	// A new key that needs other keys to be evicted first has to pass the evictor's admission policy
+	if !exists && !c.budget.hasRoomFor(extraSpaceNeeded) {
+		c.reads.flush()
+		if admitter, ok := c.evictor.(evictors.Admitter); ok && !admitter.Admit(key) {
+			c.budget.release(0, 1)
+			return 0, nil, ErrNotAdmitted
+		}
 	}
 
 	// Try to make space if the incoming item needs more space
-	for c.maxSize > 0 && c.currentSize+extraSpaceNeeded > c.maxSize {
-		if !c.makeSpaceLocked(extraSpaceNeeded) {
-			c.metrics.ErrorCount.Add(c.ctx, 1)
-			return ErrCacheFull
+	itemCount := len(c.items)
+	for !c.budget.reserveBytes(extraSpaceNeeded) {
+		if c.currentSize+extraSpaceNeeded <= c.maxSize || !c.makeSpaceLocked(extraSpaceNeeded) {
+			if !exists {
+				c.budget.release(0, 1)
+			}
+			return itemCount - len(c.items), nil, ErrCacheFull
 		}
 	}
 
-	c.setLocked(key, value, incomingItemSize)
-	return nil
+	if _, stillExists := c.items[key]; exists && !stillExists {
+		// The evictor picked the key that is being updated, it gave its space and key back to the budget
+		// that were already counted in extraSpaceNeeded. Other shards may have taken them since, so reserve them again.
+		if !c.budget.reserveKey() {
+			c.budget.release(extraSpaceNeeded, 0)
+			recordError(c.ctx, c.metrics, ErrTooManyKeys)
+			return itemCount - len(c.items), nil, ErrTooManyKeys
+		}
+		for !c.budget.reserveBytes(oldSize) {
+			if c.currentSize+incomingItemSize <= c.maxSize || !c.makeSpaceLocked(oldSize) {
+				c.budget.release(extraSpaceNeeded, 1)
+				return itemCount - len(c.items), nil, ErrCacheFull
+			}
+		}
+	}
+	removed := itemCount - len(c.items)
+
+	item := &cacheItem{
+		Key:        key,
+		Value:      value,
+		FreshUntil: freshUntil,
+		ExpiresAt:  expiresAt,
+		Size:       incomingItemSize,
+		loadCost:   w.loadCost,
+	}
+	c.setLocked(item)
+	return removed, item, nil
 }
 
-func (c *cacheShard) setLocked(key string, value []byte, itemSize int64) {
-	if oldItem, exists := c.items[key]; exists {
-		c.currentSize -= oldItem.Size
-	} else {
-		c.metrics.ItemCount.Add(c.ctx, 1)
+// itemSize returns the bytes an item is charged against the budget
+func (c *cacheShard) itemSize(key string, valueSize int) int64 {
+	if c.accounting == AccountHeap {
+		return int64(len(key)+valueSize) + c.overhead
+	}
+	return int64(valueSize)
+}
+
+// expiresAt converts a ttl into an absolute expiry time.
+// A zero time means the item never expires.
+func (c *cacheShard) expiresAt(ttl time.Duration) time.Time {
+	switch ttl {
+	case DefaultExpiration:
+		return time.Now().Add(c.ttl)
+	case NoExpiration:
+		return time.Time{}
+	default:
+		return time.Now().Add(ttl)
 	}
+}
 
-	item := &cacheItem{
-		Value:     value,
-		ExpiresAt: time.Now().Add(c.ttl),
-		Size:      itemSize,
+// staleUntil returns when an item that is fresh until freshUntil and served stale for staleFor is removed
+func (c *cacheShard) staleUntil(freshUntil time.Time, staleFor time.Duration) time.Time {
+	switch {
+	case freshUntil.IsZero():
+		return freshUntil
+	case staleFor == DefaultExpiration:
+		return freshUntil.Add(c.staleFor)
+	case staleFor == NoExpiration:
+		return time.Time{}
+	default:
+		return freshUntil.Add(staleFor)
+	}
+}
+
+func (c *cacheShard) setLocked(item *cacheItem) {
+	if oldItem, exists := c.items[item.Key]; exists {
+		c.currentSize -= oldItem.Size
+		c.expiries.untrack(oldItem)
+
+		reason := EvictedReplaced
+		if oldItem.isExpired() {
+			reason = EvictedExpired
+		}
+		c.notifyLocked(oldItem, reason)
 	}
 
-	c.items[key] = item
-	c.currentSize += itemSize
+	c.version++
+	item.Version = c.version
+
+	c.items[item.Key] = item
+	c.expiries.track(item)
+	c.currentSize += item.Size
 	c.metrics.Sets.Add(c.ctx, 1)
 
-	c.evictor.OnSet(key)
+	c.evictor.OnSet(item.Key)
 }
 
 func (c *cacheShard) get(key string) ([]byte, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	item, err := c.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
 
+// lookup only holds the read lock for the map lookup, so reads within a shard run concurrently.
+// Items are never mutated after they are stored, so the item can be returned without the lock.
+// A stale item is a hit, it is only a miss once its hard expiry has passed.
+func (c *cacheShard) lookup(key string) (*cacheItem, error) {
+	c.mu.RLock()
 	item, exists := c.items[key]
+	c.mu.RUnlock()
 
 	if !exists {
+		c.counters.misses.Add(1)
 		c.metrics.Misses.Add(c.ctx, 1)
 		return nil, ErrNotFound
 	}
 
 	// Cleanup expired items
 	if item.isExpired() {
-		c.removeKeyLocked(key)
+		c.expireItem(item)
+		c.counters.misses.Add(1)
 		c.metrics.Misses.Add(c.ctx, 1)
 		return nil, ErrExpired
 	}
 
-	c.evictor.OnGet(key)
+	if c.directReads {
+		c.evictor.OnGet(key)
+	} else {
+		c.reads.record(key)
+	}
+	c.counters.hits.Add(1)
 	c.metrics.Hits.Add(c.ctx, 1)
 
-	return item.Value, nil
+	return item, nil
 }
 
+// expireItem removes an expired item found without the write lock,
+// unless it was replaced in the meantime.
+func (c *cacheShard) expireItem(item *cacheItem) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if current, exists := c.items[item.Key]; exists && current == item {
+		c.expireKeyLocked(item.Key)
+	}
+}
+
+// applyReads replays a batch of buffered reads on the evictor.
+// Keys that were removed since they were read are ignored by the evictor.
+func (c *cacheShard) applyReads(keys []string) {
+	for _, key := range keys {
+		c.evictor.OnGet(key)
+	}
+}
+
+func (c *cacheShard) remove(key string) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	item, exists := c.items[key]
+	if !exists {
+		return ErrNotFound
+	}
+
+	// An expired item is already gone as far as callers are concerned
+	if item.isExpired() {
+		c.expireKeyLocked(key)
+		return ErrNotFound
+	}
+
+	c.removeKeyLocked(key, EvictedDeleted)
+	return nil
+}
+
+// makeSpaceLocked evicts items of this shard until neededSpace more bytes fit in the budget.
+// Returns false if the shard has nothing left to evict.
 func (c *cacheShard) makeSpaceLocked(neededSpace int64) bool {
-	c.cleanupExpiredLocked()
+	// Expired items are reclaimed first, the expiry heap only visits the items that have actually expired
+	c.removeExpiredLocked(math.MaxInt)
 
-	if c.currentSize <= c.maxSize-neededSpace {
+	if c.budget.hasRoomFor(neededSpace) {
 		return true
 	}
 
+	if !c.evictLocked(c.budget.overBy(neededSpace)) {
+		return false
+	}
+	return c.budget.hasRoomFor(neededSpace)
+}
+
+// reclaim frees up to bytes from this shard on behalf of another shard whose write did not fit in the budget.
+// Returns the number of bytes and items it removed.
+func (c *cacheShard) reclaim(bytes int64) (int64, int) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	sizeBefore, countBefore := c.currentSize, len(c.items)
+
+	c.removeExpiredLocked(math.MaxInt)
+	for freed := sizeBefore - c.currentSize; freed < bytes; {
+		// Stop once the evictor runs dry or only offers keys that are already gone
+		if !c.evictLocked(bytes-freed) || sizeBefore-c.currentSize == freed {
+			break
+		}
+		freed = sizeBefore - c.currentSize
+	}
+
+	return sizeBefore - c.currentSize, countBefore - len(c.items)
+}
+
+// evictLocked asks the evictor for enough items to free spaceToFree bytes and removes them.
+// Returns false if the evictor had nothing to evict.
+func (c *cacheShard) evictLocked(spaceToFree int64) bool {
 	// Estimate how many items to evict:
 	// For now just try to figure out the average size of each item value
 	// and calculate how many items we need to evict.
@@ -170,9 +426,10 @@ func (c *cacheShard) makeSpaceLocked(neededSpace int64) bool {
 		averageItemSize = 1
 	}
 
-	spaceToFree := c.currentSize - (c.maxSize - neededSpace)
 	countToEvict := int(spaceToFree/averageItemSize) + 1 // +1 just to be safe
 
+	// Hits still sitting in the read buffer have to reach the evictor before it picks its victims
+	c.reads.flush()
 	keysToEvict := c.evictor.Evict(countToEvict)
 	if len(keysToEvict) == 0 {
 		return false
@@ -180,35 +437,119 @@ func (c *cacheShard) makeSpaceLocked(neededSpace int64) bool {
 
 	// Remove the evicted keys from the cache.
 	for _, key := range keysToEvict {
-		c.removeKeyLocked(key)
+		c.evictKeyLocked(key)
 	}
+	return true
+}
+
+// removeExpired removes up to limit expired items, earliest expiry first.
+// It only looks at the expiry heap, so the cost is proportional to the number of expired items
+// rather than the size of the shard. Returns the number of items removed.
+func (c *cacheShard) removeExpired(limit int) int {
+	c.mu.Lock()
+	defer c.unlock()
 
-	return c.currentSize <= c.maxSize-neededSpace
+	return c.removeExpiredLocked(limit)
 }
 
-func (c *cacheShard) cleanupExpiredLocked() {
-	var keysToDelete []*string = make([]*string, 0, len(c.items))
+func (c *cacheShard) removeExpiredLocked(limit int) int {
+	now := time.Now()
+	removed := 0
 
-	for key, item := range c.items {
-		if item.isExpired() {
-			keysToDelete = append(keysToDelete, &key)
+	for removed < limit {
+		item := c.expiries.peek()
+		if item == nil || item.ExpiresAt.After(now) {
+			break
 		}
+
+		c.expireKeyLocked(item.Key)
+		removed++
+	}
+
+	return removed
+}
+
+func (c *cacheShard) expireKeyLocked(key string) {
+	c.removeKeyLocked(key, EvictedExpired)
+	c.counters.expirations.Add(1)
+	c.metrics.Expirations.Add(c.ctx, 1)
+	recordEviction(c.ctx, c.metrics, EvictedExpired)
+}
+
+// removeKeyLocked removes the key from the shard and tells the evictor to forget it
+func (c *cacheShard) removeKeyLocked(key string, reason EvictionReason) {
+	if c.dropItemLocked(key, reason) {
+		c.evictor.OnDelete(key)
 	}
+}
 
-	for _, keyToDelete := range keysToDelete {
-		c.removeKeyLocked(*keyToDelete)
+// evictKeyLocked removes a key the evictor chose to evict.
+// The evictor is not told about it again, as it may want to remember the key, as ARC does with its ghost lists.
+func (c *cacheShard) evictKeyLocked(key string) {
+	if c.dropItemLocked(key, EvictedCapacity) {
+		c.counters.evictions.Add(1)
+		recordEviction(c.ctx, c.metrics, EvictedCapacity)
 	}
 }
 
-func (c *cacheShard) removeKeyLocked(key string) {
+func (c *cacheShard) dropItemLocked(key string, reason EvictionReason) bool {
 	item, exists := c.items[key]
 
 	if !exists {
-		return
+		return false
 	}
 
 	c.currentSize -= item.Size
+	c.budget.release(item.Size, 1)
+	c.expiries.untrack(item)
 	delete(c.items, key)
+	c.notifyLocked(item, reason)
+	return true
+}
+
+// size returns the bytes stored in the shard
+func (c *cacheShard) size() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.currentSize
+}
+
+// stats returns the shard's usage and counters
+func (c *cacheShard) stats() ShardStats {
+	c.mu.RLock()
+	keys, bytes := len(c.items), c.currentSize
+	c.mu.RUnlock()
+
+	return ShardStats{
+		ID:          c.id,
+		Keys:        keys,
+		Bytes:       bytes,
+		MaxKeys:     c.maxKeys,
+		MaxBytes:    c.maxSize,
+		Hits:        c.counters.hits.Load(),
+		Misses:      c.counters.misses.Load(),
+		Evictions:   c.counters.evictions.Load(),
+		Expirations: c.counters.expirations.Load(),
+	}
+}
 
-	c.metrics.ItemCount.Add(c.ctx, -1)
+// notifyLocked queues the removed item for the eviction callback
+func (c *cacheShard) notifyLocked(item *cacheItem, reason EvictionReason) {
+	if c.onEvict == nil {
+		return
+	}
+	c.evicted = append(c.evicted, evictedEntry{key: item.Key, value: item.Value, reason: reason})
+}
+
+// unlock releases the write lock and then calls onEvict for the entries removed while it was held,
+// so the callback is free to call back into the cache.
+func (c *cacheShard) unlock() {
+	evicted := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+
+	for _, entry := range evicted {
+		c.onEvict(entry.key, entry.value, entry.reason)
+	}
 }
@@ -1,40 +1,52 @@
 package cache
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"cache-service/internal/evictors"
 	"cache-service/internal/telemetry"
+)
 
-	"github.com/google/uuid"
-	"stathat.com/c/consistent"
+// FNV-1a parameters, see shardIndex
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
 )
 
 type shardManager struct {
-	shardMap   map[string]*cacheShard
-	ring       *consistent.Consistent
-	shardCount int
+	shards []*cacheShard
+	budget *budget // maxSize and maxKeys of the whole cache, shared by the shards
 }
 
+// newShardManager creates shardCount shards that share a budget of maxSize bytes and maxKeys keys,
+// items are charged against it as set by accounting
 func newShardManager(
 	ctx context.Context,
 	shardCount int,
 	ttl time.Duration,
-	maxSizePerShard int64,
-	maxKeysPerShard int,
+	staleFor time.Duration,
+	maxSize int64,
+	maxKeys int,
+	accounting SizeAccounting,
 	evictorFactory func() evictors.Evictor,
 	metrics *telemetry.CacheMetrics,
+	onEvict EvictionFunc,
 ) (*shardManager, error) {
 
-	shardMap := make(map[string]*cacheShard, shardCount)
-	shardNodes := make([]string, 0, shardCount)
-	ring := consistent.New()
+	shards := make([]*cacheShard, 0, shardCount)
+	sharedBudget := newBudget(maxSize, maxKeys)
+
+	// Each shard is sized for its fair share, the limit that decides whether it evicts its own items
+	maxSizePerShard := max(1, maxSize/int64(shardCount))
+	maxKeysPerShard := max(1, maxKeys/shardCount)
 
-	for range shardCount {
-		shardID := uuid.New().String()
-		shardNodes = append(shardNodes, shardID)
+	for i := range shardCount {
+		// The id only depends on the position, so a key maps to the same shard id after every restart
+		shardID := fmt.Sprintf("shard-%d", i)
 
 		// Create a new instance of evictor per shard
 		// Reuse the same metrics instance as we want to aggregate metrics across shards
@@ -43,31 +55,59 @@ func newShardManager(
 		if err != nil {
 			return nil, fmt.Errorf("failed to create shard: %w", err)
 		}
+		shard.onEvict = onEvict
+		shard.budget = sharedBudget
+		shard.accounting = accounting
+		shard.staleFor = staleFor
 
-		shardMap[shardID] = shard
+		shards = append(shards, shard)
 	}
 
-	// Sets the shards on an consistent hasing ring ensure even distribution
-	// TODO: We can still may have unevent distribution, but this is good enough for now
-	ring.Set(shardNodes)
-
 	return &shardManager{
-		shardMap:   shardMap,
-		ring:       ring,
-		shardCount: shardCount,
+		shards: shards,
+		budget: sharedBudget,
 	}, nil
 }
 
-func (sm *shardManager) GetShard(shardKey string) *cacheShard {
-	shardID, err := sm.ring.Get(shardKey)
+// reclaim evicts at least bytes from the shards holding the most data, largest first.
+// It is used when a write does not fit in the budget while its own shard is within its fair share.
+// Returns the number of items removed.
+func (sm *shardManager) reclaim(bytes int64) int {
+	shards := slices.Clone(sm.shards)
+	sizes := make(map[*cacheShard]int64, len(shards))
+	for _, shard := range shards {
+		sizes[shard] = shard.size()
+	}
+	slices.SortFunc(shards, func(a, b *cacheShard) int { return cmp.Compare(sizes[b], sizes[a]) })
 
-	if err != nil {
-		// TODO: Implement robust fallback as this may result in hot spots
-		// For now, we return the first shard as a fallback
-		for _, shard := range sm.shardMap {
-			return shard
+	var freed int64
+	removed := 0
+	for _, shard := range shards {
+		if freed >= bytes || sizes[shard] == 0 {
+			break
 		}
+		shardFreed, shardRemoved := shard.reclaim(bytes - freed)
+		freed += shardFreed
+		removed += shardRemoved
 	}
+	return removed
+}
 
-	return sm.shardMap[shardID]
+func (sm *shardManager) GetShard(shardKey string) *cacheShard {
+	return sm.shards[shardIndex(shardKey, len(sm.shards))]
+}
+
+// shardIndex maps a key to one of shardCount shards with hash(key) % shardCount.
+// The shards live in one process and their number never changes while it runs,
+// so a consistent hashing ring buys nothing here: it spreads keys less evenly across
+// hundreds of shards and its lookup is an order of magnitude slower, see BenchmarkShardLookup
+// and BenchmarkShardDistribution. FNV-1a is inlined to keep the lookup free of allocations,
+// and unlike maphash it is not seeded, so placement is the same on every restart.
+func shardIndex(key string, shardCount int) int {
+	hash := uint64(fnvOffset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= fnvPrime64
+	}
+	return int(hash % uint64(shardCount))
 }
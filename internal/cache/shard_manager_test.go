@@ -2,21 +2,20 @@ package cache
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"cache-service/internal/evictors"
-
-	"stathat.com/c/consistent"
 )
 
 func TestShardManager(t *testing.T) {
 	metrics := createTestMetrics(t)
-	shardManager, err := newShardManager(context.Background(), 8, time.Minute, 128, 10, NewLRUEvictor, metrics)
+	shardManager, err := newShardManager(context.Background(), 8, time.Minute, 0, 128, 10, AccountPayload, NewLRUEvictor, metrics, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if len(shardManager.shardMap) != 8 {
+	if len(shardManager.shards) != 8 {
 		t.Fatalf("expected 8 shards")
 	}
 
@@ -29,16 +28,45 @@ func TestShardManager(t *testing.T) {
 
 func NewLRUEvictor() evictors.Evictor { return evictors.NewLRUEvictor() }
 
-func TestShardManagerFallback(t *testing.T) {
+func TestShardManagerDeterministicIDs(t *testing.T) {
 	metrics := createTestMetrics(t)
-	shardManager, err := newShardManager(context.Background(), 1, time.Minute, 128, 10, NewLRUEvictor, metrics)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	first, _ := newShardManager(context.Background(), 16, time.Minute, 0, 128, 10, AccountPayload, NewLRUEvictor, metrics, nil)
+	second, _ := newShardManager(context.Background(), 16, time.Minute, 0, 128, 10, AccountPayload, NewLRUEvictor, metrics, nil)
+
+	for i, shard := range first.shards {
+		if shard.id != fmt.Sprintf("shard-%d", i) {
+			t.Fatalf("expected shard-%d, got %s", i, shard.id)
+		}
+	}
+
+	// A restarted cache must place every key on the shard with the same id
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		if first.GetShard(key).id != second.GetShard(key).id {
+			t.Fatalf("expected %s to be placed on the same shard", key)
+		}
+	}
+}
+
+func TestShardIndexDistribution(t *testing.T) {
+	const shardCount, keyCount = 256, 100_000
+
+	counts := make([]int, shardCount)
+	for i := range keyCount {
+		counts[shardIndex(fmt.Sprintf("user:%d", i), shardCount)]++
 	}
 
-	shardManager.ring = consistent.New()
-	shard := shardManager.GetShard("key")
-	if shard == nil {
-		t.Fatalf("expected fallback shard")
+	highest := 0
+	for _, count := range counts {
+		highest = max(highest, count)
+	}
+	if skew := float64(highest) / (keyCount / shardCount); skew > 1.2 {
+		t.Fatalf("expected keys to spread evenly, busiest shard holds %.2fx the mean", skew)
+	}
+}
+
+func TestShardIndexSingleShard(t *testing.T) {
+	if index := shardIndex("key", 1); index != 0 {
+		t.Fatalf("expected every key on the only shard, got %d", index)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"cache-service/internal/evictors"
 )
 
 func TestShardSetGet(t *testing.T) {
@@ -37,6 +39,26 @@ func TestShardExpiration(t *testing.T) {
 	}
 }
 
+func TestShardSetWithTTL(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 1024, 10, newLRUEvictorForTest(), metrics)
+
+	if err := shard.setWithTTL("a", []byte("b"), NoExpiration); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	if !shard.items["a"].ExpiresAt.IsZero() {
+		t.Fatalf("expected no expiry for NoExpiration")
+	}
+
+	before := time.Now()
+	if err := shard.setWithTTL("a", []byte("b"), 5*time.Second); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	if expiresAt := shard.items["a"].ExpiresAt; expiresAt.Before(before.Add(5*time.Second)) || expiresAt.After(time.Now().Add(5*time.Second)) {
+		t.Fatalf("unexpected expiry %v", expiresAt)
+	}
+}
+
 func TestMakeSpaceLocked(t *testing.T) {
 	metrics := createTestMetrics(t)
 	shard, _ := newShard(context.Background(), "s1", time.Minute, 5, 2, newLRUEvictorForTest(), metrics)
@@ -53,6 +75,59 @@ func TestMakeSpaceLocked(t *testing.T) {
 	}
 }
 
+func TestMakeSpaceLockedReclaimsExpired(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 6, 10, newLRUEvictorForTest(), metrics)
+
+	shard.set("live", []byte("aa"))
+	shard.setWithTTL("old1", []byte("bb"), time.Millisecond)
+	shard.setWithTTL("old2", []byte("cc"), time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	shard.mu.Lock()
+	ok := shard.makeSpaceLocked(4)
+	shard.mu.Unlock()
+
+	if !ok {
+		t.Fatalf("expected to free space")
+	}
+
+	// Reclaiming the expired items is enough, the live item must not be evicted
+	if _, exists := shard.items["live"]; !exists {
+		t.Fatalf("expected live item to survive")
+	}
+	if len(shard.items) != 1 || shard.expiries.Len() != 1 {
+		t.Fatalf("expected only the live item to remain, got %d items", len(shard.items))
+	}
+}
+
+func TestShardSetRejectedByAdmission(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 2, 10, evictors.NewTinyLFUEvictor(), metrics)
+
+	// A few reads are enough, they are flushed to the evictor before it decides on admission
+	shard.set("hot", []byte("aa"))
+	for range 3 {
+		shard.get("hot")
+	}
+
+	if err := shard.set("cold", []byte("bb")); err != ErrNotAdmitted {
+		t.Fatalf("expected ErrNotAdmitted, got %v", err)
+	}
+	if _, exists := shard.items["cold"]; exists {
+		t.Fatalf("expected rejected key not to be stored")
+	}
+	if _, err := shard.get("hot"); err != nil {
+		t.Fatalf("expected hot key to stay, got %v", err)
+	}
+
+	// Updating a key that is already cached never goes through admission
+	if err := shard.set("hot", []byte("cc")); err != nil {
+		t.Fatalf("expected update to succeed, got %v", err)
+	}
+}
+
 func TestShardGetNotFound(t *testing.T) {
 	metrics := createTestMetrics(t)
 	shard, _ := newShard(context.Background(), "s1", time.Minute, 10, 10, newLRUEvictorForTest(), metrics)
@@ -88,3 +163,226 @@ func TestShardSetOversized(t *testing.T) {
 		t.Fatalf("expected ErrValueTooLarge")
 	}
 }
+
+func TestShardRemove(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 10, 10, newLRUEvictorForTest(), metrics)
+
+	shard.set("a", []byte("aa"))
+	shard.set("b", []byte("bb"))
+
+	if err := shard.remove("a"); err != nil {
+		t.Fatalf("remove error: %v", err)
+	}
+
+	if shard.currentSize != 2 {
+		t.Fatalf("expected current size 2, got %d", shard.currentSize)
+	}
+
+	// The evictor must have forgotten the removed key, so only b is left to evict
+	if keys := shard.evictor.Evict(2); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("expected only b to be evictable, got %v", keys)
+	}
+
+	if err := shard.remove("missing"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestShardRemoveExpired(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", 10*time.Millisecond, 10, 10, newLRUEvictorForTest(), metrics)
+
+	shard.set("a", []byte("aa"))
+	time.Sleep(20 * time.Millisecond)
+
+	if err := shard.remove("a"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for expired key, got %v", err)
+	}
+
+	if len(shard.items) != 0 || shard.currentSize != 0 {
+		t.Fatalf("expected expired key to be removed")
+	}
+}
+
+func TestShardRemoveExpiredBatch(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 1024, 10, newLRUEvictorForTest(), metrics)
+
+	shard.setWithTTL("a", []byte("1"), time.Millisecond)
+	shard.setWithTTL("b", []byte("2"), time.Millisecond)
+	shard.setWithTTL("c", []byte("3"), time.Millisecond)
+	shard.setWithTTL("d", []byte("4"), time.Hour)
+	shard.setWithTTL("e", []byte("5"), NoExpiration)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if removed := shard.removeExpired(2); removed != 2 {
+		t.Fatalf("expected the batch limit of 2 to be removed, got %d", removed)
+	}
+	if removed := shard.removeExpired(10); removed != 1 {
+		t.Fatalf("expected the last expired item to be removed, got %d", removed)
+	}
+	if removed := shard.removeExpired(10); removed != 0 {
+		t.Fatalf("expected nothing left to remove, got %d", removed)
+	}
+
+	if len(shard.items) != 2 || shard.currentSize != 2 {
+		t.Fatalf("expected only d and e to remain, got %d items of size %d", len(shard.items), shard.currentSize)
+	}
+	if shard.expiries.Len() != 1 {
+		t.Fatalf("expected only d on the expiry heap, got %d", shard.expiries.Len())
+	}
+}
+
+func TestShardOverwriteUpdatesExpiry(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 1024, 10, newLRUEvictorForTest(), metrics)
+
+	shard.setWithTTL("a", []byte("1"), time.Millisecond)
+	shard.setWithTTL("a", []byte("2"), NoExpiration)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if removed := shard.removeExpired(10); removed != 0 {
+		t.Fatalf("expected overwritten item to no longer expire, removed %d", removed)
+	}
+	if shard.expiries.Len() != 0 {
+		t.Fatalf("expected expiry heap to be empty, got %d", shard.expiries.Len())
+	}
+}
+
+func TestShardGetRecordsRecency(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 1024, 10, newLRUEvictorForTest(), metrics)
+
+	shard.set("a", []byte("1"))
+	shard.set("b", []byte("2"))
+
+	// Reads are buffered, a full batch reaches the evictor and makes a the most recently used key
+	for range readBufferSize {
+		shard.get("a")
+	}
+
+	if keys := shard.evictor.Evict(1); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", keys)
+	}
+}
+
+func TestShardGetSkipsReadBufferForLockFreeEvictor(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 1024, 10, evictors.NewSieveEvictor(), metrics)
+
+	shard.set("a", []byte("1"))
+	shard.set("b", []byte("2"))
+	shard.get("a")
+
+	// The single hit reached the evictor right away, so a is visited and b goes first
+	if keys := shard.evictor.Evict(1); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", keys)
+	}
+	for i := range shard.reads.stripes {
+		if keys := shard.reads.stripes[i].keys; len(keys) != 0 {
+			t.Fatalf("expected no reads to be buffered, got %v", keys)
+		}
+	}
+}
+
+func TestShardGetExpiredRemovesItem(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Millisecond, 1024, 10, newLRUEvictorForTest(), metrics)
+
+	shard.set("a", []byte("1"))
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := shard.get("a"); err != ErrExpired {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if len(shard.items) != 0 || shard.currentSize != 0 {
+		t.Fatalf("expected expired item to be removed on read")
+	}
+}
+
+type capacityAwareEvictor struct {
+	testEvictor
+	capacity int
+}
+
+func (e *capacityAwareEvictor) SetCapacity(keys int) { e.capacity = keys }
+
+func TestNewShardSetsEvictorCapacity(t *testing.T) {
+	metrics := createTestMetrics(t)
+	evictor := &capacityAwareEvictor{}
+
+	if _, err := newShard(context.Background(), "s1", time.Minute, 1024, 42, evictor, metrics); err != nil {
+		t.Fatalf("newShard error: %v", err)
+	}
+	if evictor.capacity != 42 {
+		t.Fatalf("expected evictor capacity to be the shard's key capacity, got %d", evictor.capacity)
+	}
+}
+
+type deleteRecordingEvictor struct {
+	*evictors.LRUEvictor
+	deleted []string
+}
+
+func (e *deleteRecordingEvictor) OnDelete(key string) {
+	e.deleted = append(e.deleted, key)
+	e.LRUEvictor.OnDelete(key)
+}
+
+func TestShardEvictionDoesNotDeleteFromEvictor(t *testing.T) {
+	metrics := createTestMetrics(t)
+	evictor := &deleteRecordingEvictor{LRUEvictor: evictors.NewLRUEvictor()}
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 2, 10, evictor, metrics)
+
+	shard.set("a", []byte("1"))
+	shard.set("b", []byte("2"))
+	shard.set("c", []byte("3"))
+
+	if _, exists := shard.items["a"]; exists {
+		t.Fatalf("expected a to be evicted")
+	}
+
+	// Evictors such as ARC remember evicted keys, deleting them would wipe that history
+	if len(evictor.deleted) != 0 {
+		t.Fatalf("expected evicted keys not to be deleted from the evictor, got %v", evictor.deleted)
+	}
+
+	shard.remove("c")
+	if len(evictor.deleted) != 1 || evictor.deleted[0] != "c" {
+		t.Fatalf("expected removed key to be deleted from the evictor, got %v", evictor.deleted)
+	}
+}
+
+func TestShardOnEvictReasons(t *testing.T) {
+	metrics := createTestMetrics(t)
+	shard, _ := newShard(context.Background(), "s1", time.Minute, 4, 10, newLRUEvictorForTest(), metrics)
+
+	var got []string
+	shard.onEvict = func(key string, value []byte, reason EvictionReason) {
+		got = append(got, key+"="+string(value)+":"+reason.String())
+	}
+
+	shard.set("a", []byte("1"))
+	shard.set("a", []byte("2"))
+	shard.remove("a")
+
+	shard.setWithTTL("b", []byte("3"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	shard.removeExpired(10)
+
+	shard.set("c", []byte("4444"))
+	shard.set("d", []byte("5"))
+
+	expected := []string{"a=1:replaced", "a=2:deleted", "b=3:expired", "c=4444:capacity"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
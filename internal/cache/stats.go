@@ -0,0 +1,84 @@
+package cache
+
+// Stats is a snapshot of the cache's usage, the totals are the sums over the shards.
+// Shards are read one after the other, so the snapshot is not atomic across shards.
+type Stats struct {
+	Keys        int          `json:"keys"`
+	Bytes       int64        `json:"bytes"`
+	MaxKeys     int          `json:"max_keys"`
+	MaxBytes    int64        `json:"max_bytes"`
+	Accounting  string       `json:"size_accounting"` // what bytes counts, payload or heap
+	Hits        int64        `json:"hits"`
+	Misses      int64        `json:"misses"`
+	Evictions   int64        `json:"evictions"`
+	Expirations int64        `json:"expirations"`
+	Skew        Skew         `json:"skew"`
+	Shards      []ShardStats `json:"shards"`
+}
+
+// ShardStats is the usage of a single shard
+type ShardStats struct {
+	ID          string `json:"id"`
+	Keys        int    `json:"keys"`
+	Bytes       int64  `json:"bytes"`
+	MaxKeys     int    `json:"max_keys"`
+	MaxBytes    int64  `json:"max_bytes"`
+	Hits        int64  `json:"hits"`
+	Misses      int64  `json:"misses"`
+	Evictions   int64  `json:"evictions"`
+	Expirations int64  `json:"expirations"`
+}
+
+// Skew compares the busiest shard with the average shard as max/mean ratios.
+// A ratio of 1 means the shards are perfectly even, a ratio of 0 means there is nothing to compare yet.
+// A high key or byte ratio points at an uneven ring, a high request ratio at hot keys.
+type Skew struct {
+	Keys     float64 `json:"keys"`
+	Bytes    float64 `json:"bytes"`
+	Requests float64 `json:"requests"` // hits and misses
+}
+
+// Stats returns the usage of the cache and each of its shards, in shard order
+func (c *Cache) Stats() Stats {
+	stats := Stats{Shards: make([]ShardStats, 0, len(c.shardManager.shards))}
+
+	for _, shard := range c.shardManager.shards {
+		shardStats := shard.stats()
+
+		stats.Keys += shardStats.Keys
+		stats.Bytes += shardStats.Bytes
+		stats.Hits += shardStats.Hits
+		stats.Misses += shardStats.Misses
+		stats.Evictions += shardStats.Evictions
+		stats.Expirations += shardStats.Expirations
+
+		stats.Shards = append(stats.Shards, shardStats)
+	}
+
+	// The fair shares of the shards can round down, the budget holds the configured capacity
+	stats.MaxKeys = int(c.shardManager.budget.maxKeys)
+	stats.MaxBytes = c.shardManager.budget.maxBytes
+	stats.Accounting = c.accounting.String()
+
+	stats.Skew = Skew{
+		Keys:     maxMeanRatio(stats.Shards, func(s ShardStats) int64 { return int64(s.Keys) }),
+		Bytes:    maxMeanRatio(stats.Shards, func(s ShardStats) int64 { return s.Bytes }),
+		Requests: maxMeanRatio(stats.Shards, func(s ShardStats) int64 { return s.Hits + s.Misses }),
+	}
+	return stats
+}
+
+func maxMeanRatio(shards []ShardStats, value func(ShardStats) int64) float64 {
+	var total, highest int64
+	for _, shard := range shards {
+		v := value(shard)
+		total += v
+		highest = max(highest, v)
+	}
+
+	if total == 0 {
+		return 0
+	}
+	mean := float64(total) / float64(len(shards))
+	return float64(highest) / mean
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheStats(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(4), WithMaxSize(400), WithMaxKeys(40), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.Set("a", []byte("1234"))
+	cacheInstance.Set("b", []byte("12"))
+	cacheInstance.SetWithTTL("c", []byte("1"), time.Millisecond)
+	cacheInstance.Get("a")
+	cacheInstance.Get("missing")
+
+	time.Sleep(5 * time.Millisecond)
+	cacheInstance.Get("c")
+
+	stats := cacheInstance.Stats()
+
+	if stats.Keys != 2 || stats.Bytes != 6 || stats.MaxKeys != 40 || stats.MaxBytes != 400 {
+		t.Fatalf("unexpected usage %+v", stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Expirations != 1 || stats.Evictions != 0 {
+		t.Fatalf("unexpected counters %+v", stats)
+	}
+	if len(stats.Shards) != 4 {
+		t.Fatalf("expected 4 shards, got %d", len(stats.Shards))
+	}
+
+	var keys int
+	for i, shard := range stats.Shards {
+		keys += shard.Keys
+		if shard.ID != fmt.Sprintf("shard-%d", i) {
+			t.Fatalf("expected shards in order, got %s at %d", shard.ID, i)
+		}
+	}
+	if keys != stats.Keys {
+		t.Fatalf("expected the shard keys to add up to %d, got %d", stats.Keys, keys)
+	}
+}
+
+func TestCacheStatsCountsEvictions(t *testing.T) {
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(4), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.Set("a", []byte("1234"))
+	cacheInstance.Set("b", []byte("1"))
+
+	if stats := cacheInstance.Stats(); stats.Evictions != 1 || stats.Shards[0].Evictions != 1 {
+		t.Fatalf("expected one eviction, got %+v", stats)
+	}
+}
+
+func TestMaxMeanRatio(t *testing.T) {
+	keys := func(s ShardStats) int64 { return int64(s.Keys) }
+
+	if ratio := maxMeanRatio([]ShardStats{{Keys: 5}, {Keys: 5}}, keys); ratio != 1 {
+		t.Fatalf("expected even shards to have a ratio of 1, got %v", ratio)
+	}
+	if ratio := maxMeanRatio([]ShardStats{{Keys: 9}, {Keys: 1}, {Keys: 2}}, keys); ratio != 2.25 {
+		t.Fatalf("expected a ratio of 2.25, got %v", ratio)
+	}
+	if ratio := maxMeanRatio([]ShardStats{{}, {}}, keys); ratio != 0 {
+		t.Fatalf("expected empty shards to have a ratio of 0, got %v", ratio)
+	}
+}
@@ -1,11 +1,15 @@
 package cache
 
 import (
+	"context"
 	"testing"
 
 	"cache-service/internal/evictors"
 	"cache-service/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 func newLRUEvictorForTest() evictors.Evictor { return evictors.NewLRUEvictor() }
@@ -18,3 +22,82 @@ func createTestMetrics(tb testing.TB) *telemetry.CacheMetrics {
 	}
 	return metrics
 }
+
+// createRecordedTestMetrics returns metrics backed by an in-memory reader, so tests can assert on what was recorded
+func createRecordedTestMetrics(tb testing.TB) (*telemetry.CacheMetrics, *sdkmetric.ManualReader) {
+	tb.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	metrics, err := telemetry.NewCacheMetrics(provider.Meter("test"))
+	if err != nil {
+		tb.Fatalf("failed to create metrics: %v", err)
+	}
+	return metrics, reader
+}
+
+// collectSum returns the value of the named counter for the data point with the given attribute
+func collectSum(tb testing.TB, reader *sdkmetric.ManualReader, name string, attr attribute.KeyValue) int64 {
+	tb.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != name || !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				if value, found := point.Attributes.Value(attr.Key); found && value == attr.Value {
+					return point.Value
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// collectHistogramCount returns how many measurements the named histogram holds for the given attributes
+func collectHistogramCount(tb testing.TB, reader *sdkmetric.ManualReader, name string, attrs ...attribute.KeyValue) uint64 {
+	tb.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	expected := attribute.NewSet(attrs...)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			histogram, ok := m.Data.(metricdata.Histogram[float64])
+			if m.Name != name || !ok {
+				continue
+			}
+			for _, point := range histogram.DataPoints {
+				if point.Attributes.Equals(&expected) {
+					return point.Count
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// collectGauge returns the value of the named int64 gauge
+func collectGauge(tb testing.TB, reader *sdkmetric.ManualReader, name string) int64 {
+	tb.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if gauge, ok := m.Data.(metricdata.Gauge[int64]); ok && m.Name == name && len(gauge.DataPoints) > 0 {
+				return gauge.DataPoints[0].Value
+			}
+		}
+	}
+	return 0
+}
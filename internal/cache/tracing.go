@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the cache spans
+const tracerName = "cache-service/cache"
+
+// Attributes set on the cache spans
+const (
+	shardIDKey   = attribute.Key("cache.shard_id")
+	hitKey       = attribute.Key("cache.hit")
+	valueSizeKey = attribute.Key("cache.value_size")
+	evictedKey   = attribute.Key("cache.evicted_items")
+	sharedKey    = attribute.Key("cache.load_shared")
+	staleKey     = attribute.Key("cache.stale")
+	refreshKey   = attribute.Key("cache.refresh")
+)
+
+// endGetSpan annotates the span of a Get, a missing or expired key is a miss rather than an error.
+// refreshing is set when the read started a background refresh of the value.
+func endGetSpan(span trace.Span, shardID string, entry Entry, refreshing bool, err error) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(shardIDKey.String(shardID), hitKey.Bool(err == nil))
+	if err == nil {
+		span.SetAttributes(
+			valueSizeKey.Int(len(entry.Value)),
+			staleKey.Bool(entry.Stale),
+			refreshKey.Bool(refreshing),
+		)
+		return
+	}
+	if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired) {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// endSetSpan annotates the span of a Set with the number of items removed to make space for the value,
+// a conditional write that was not applied is not an error
+func endSetSpan(span trace.Span, shardID string, value []byte, evicted int, err error) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		shardIDKey.String(shardID),
+		valueSizeKey.Int(len(value)),
+		evictedKey.Int(evicted),
+	)
+	if err != nil && !isConditionFailure(err) {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// endLoadSpan annotates the span of a GetOrLoad, shared means the caller waited for a load started by another caller
+func endLoadSpan(span trace.Span, hit bool, shared bool, err error) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(hitKey.Bool(hit))
+	if !hit {
+		span.SetAttributes(sharedKey.Bool(shared))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestCacheSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(4), WithTracerProvider(provider), WithMetrics(createTestMetrics(t)))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	cacheInstance.SetWithTTLContext(ctx, "a", []byte("1111"), DefaultExpiration)
+	cacheInstance.SetWithTTLContext(ctx, "b", []byte("22"), DefaultExpiration)
+	cacheInstance.GetContext(ctx, "b")
+	cacheInstance.GetContext(ctx, "a")
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 5 {
+		t.Fatalf("expected 4 cache spans and the parent, got %d", len(spans))
+	}
+
+	setA, setB, getB, getA := spans[0], spans[1], spans[2], spans[3]
+	for _, span := range spans[:4] {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("expected %s to be a child of the request span", span.Name())
+		}
+		if spanAttribute(span, shardIDKey).AsString() == "" {
+			t.Fatalf("expected %s to carry the shard id", span.Name())
+		}
+	}
+
+	if setA.Name() != "cache.Set" || spanAttribute(setA, evictedKey).AsInt64() != 0 || spanAttribute(setA, valueSizeKey).AsInt64() != 4 {
+		t.Fatalf("unexpected attributes on the first set: %v", setA.Attributes())
+	}
+	if spanAttribute(setB, evictedKey).AsInt64() != 1 {
+		t.Fatalf("expected the second set to evict a, got %v", setB.Attributes())
+	}
+	if getB.Name() != "cache.Get" || !spanAttribute(getB, hitKey).AsBool() || spanAttribute(getB, valueSizeKey).AsInt64() != 2 {
+		t.Fatalf("unexpected attributes on the hit: %v", getB.Attributes())
+	}
+	if spanAttribute(getA, hitKey).AsBool() || getA.Status().Code == codes.Error {
+		t.Fatalf("expected a miss that is not an error, got %v %v", getA.Attributes(), getA.Status())
+	}
+}
+
+func TestCacheSetSpanRecordsError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cacheInstance, _ := NewCache(context.Background(), WithShardCount(1), WithMaxSize(4), WithTracerProvider(provider), WithMetrics(createTestMetrics(t)))
+
+	cacheInstance.Set("a", []byte("too large"))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("expected a failed set span, got %v", spans)
+	}
+}
+
+func TestGetOrLoadSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cacheInstance, _ := NewCache(context.Background(), WithTracerProvider(provider), WithMetrics(createTestMetrics(t)))
+
+	loader := func(context.Context) ([]byte, time.Duration, error) {
+		return []byte("value"), DefaultExpiration, nil
+	}
+	cacheInstance.GetOrLoad(context.Background(), "key", loader)
+	cacheInstance.GetOrLoad(context.Background(), "key", loader)
+
+	var loads []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "cache.GetOrLoad" {
+			loads = append(loads, span)
+		}
+	}
+	if len(loads) != 2 {
+		t.Fatalf("expected 2 GetOrLoad spans, got %d", len(loads))
+	}
+	if spanAttribute(loads[0], hitKey).AsBool() || spanAttribute(loads[0], sharedKey).AsBool() {
+		t.Fatalf("expected the first call to miss and run the load, got %v", loads[0].Attributes())
+	}
+	if !spanAttribute(loads[1], hitKey).AsBool() {
+		t.Fatalf("expected the second call to hit, got %v", loads[1].Attributes())
+	}
+}
@@ -6,15 +6,22 @@ import (
 	"strconv"
 	"time"
 
+	"cache-service/internal/cache"
 	"cache-service/internal/evictors"
+	"cache-service/internal/telemetry"
 )
 
 type Config struct {
-	Port           int
-	CacheTTL       time.Duration
-	MaxCacheSize   int64
-	MaxKeys        int
-	EvictorFactory func() evictors.Evictor
+	Port                int
+	CacheTTL            time.Duration
+	ExpirySweepInterval time.Duration
+	MaxCacheSize        int64
+	MaxKeys             int
+	SizeAccounting      cache.SizeAccounting
+	EvictionPolicy      string
+	EvictorFactory      func() evictors.Evictor
+	MetricsExporter     string
+	TracesExporter      string
 }
 
 // LoadConfig loads the configuration from environment variables with defaults.
@@ -31,6 +38,10 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err = loadEnvVar(&cfg.ExpirySweepInterval, "EXPIRY_SWEEP_INTERVAL", time.ParseDuration); err != nil {
+		return nil, err
+	}
+
 	if err = loadEnvVar(&cfg.MaxCacheSize, "MAX_CACHE_SIZE", func(s string) (int64, error) { return strconv.ParseInt(s, 10, 64) }); err != nil {
 		return nil, err
 	}
@@ -39,20 +50,44 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err = loadEnvVar(&cfg.SizeAccounting, "SIZE_ACCOUNTING", cache.ParseSizeAccounting); err != nil {
+		return nil, err
+	}
+
+	if err = loadEnvVar(&cfg.EvictionPolicy, "EVICTION_POLICY", func(s string) (string, error) { return s, nil }); err != nil {
+		return nil, err
+	}
+
+	if err = loadEnvVar(&cfg.MetricsExporter, "METRICS_EXPORTER", func(s string) (string, error) { return s, nil }); err != nil {
+		return nil, err
+	}
+
+	if err = loadEnvVar(&cfg.TracesExporter, "TRACES_EXPORTER", func(s string) (string, error) { return s, nil }); err != nil {
+		return nil, err
+	}
+
 	if err := validateConfig(cfg); err != nil {
 		return nil, fmt.Errorf("configuration validation error: %w", err)
 	}
 
+	// The policy was validated above, so the lookup cannot fail here
+	cfg.EvictorFactory, _ = evictors.Lookup(cfg.EvictionPolicy)
+
 	return cfg, nil
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Port:           8080,
-		CacheTTL:       30 * time.Minute,
-		MaxCacheSize:   1024 * 1024 * 1024,
-		MaxKeys:        2_000_000,
-		EvictorFactory: func() evictors.Evictor { return evictors.NewLRUEvictor() },
+		Port:                8080,
+		CacheTTL:            30 * time.Minute,
+		ExpirySweepInterval: time.Second,
+		MaxCacheSize:        1024 * 1024 * 1024,
+		MaxKeys:             2_000_000,
+		SizeAccounting:      cache.AccountPayload,
+		EvictionPolicy:      "lru",
+		EvictorFactory:      func() evictors.Evictor { return evictors.NewLRUEvictor() },
+		MetricsExporter:     telemetry.ExporterPrometheus,
+		TracesExporter:      telemetry.ExporterNone,
 	}
 }
 
@@ -76,12 +111,24 @@ func validateConfig(cfg *Config) error {
 	if cfg.CacheTTL <= 0 {
 		return fmt.Errorf("CACHE_TTL must be a positive duration, got %s", cfg.CacheTTL)
 	}
+	if cfg.ExpirySweepInterval <= 0 {
+		return fmt.Errorf("EXPIRY_SWEEP_INTERVAL must be a positive duration, got %s", cfg.ExpirySweepInterval)
+	}
 	if cfg.MaxCacheSize <= 0 {
 		return fmt.Errorf("MAX_CACHE_SIZE must be a positive integer, got %d", cfg.MaxCacheSize)
 	}
 	if cfg.MaxKeys <= 0 {
 		return fmt.Errorf("MAX_KEYS must be a positive integer, got %d", cfg.MaxKeys)
 	}
+	if _, err := evictors.Lookup(cfg.EvictionPolicy); err != nil {
+		return fmt.Errorf("EVICTION_POLICY is invalid: %w", err)
+	}
+	if err := telemetry.ValidateMetricsExporter(cfg.MetricsExporter); err != nil {
+		return fmt.Errorf("METRICS_EXPORTER is invalid: %w", err)
+	}
+	if err := telemetry.ValidateTracesExporter(cfg.TracesExporter); err != nil {
+		return fmt.Errorf("TRACES_EXPORTER is invalid: %w", err)
+	}
 
 	return nil
 }
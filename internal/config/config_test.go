@@ -4,6 +4,9 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"cache-service/internal/cache"
+	"cache-service/internal/evictors"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -18,6 +21,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.MaxCacheSize == 0 {
 		t.Fatalf("max cache size not set")
 	}
+	if cfg.EvictionPolicy != "lru" || cfg.EvictorFactory == nil {
+		t.Fatalf("expected lru eviction policy by default")
+	}
 }
 
 func TestLoadConfig(t *testing.T) {
@@ -50,6 +56,24 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigExpirySweepInterval(t *testing.T) {
+	t.Setenv("EXPIRY_SWEEP_INTERVAL", "250ms")
+
+	cfg, err := LoadConfig()
+
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.ExpirySweepInterval != 250*time.Millisecond {
+		t.Errorf("expected sweep interval 250ms, got %s", cfg.ExpirySweepInterval)
+	}
+
+	t.Setenv("EXPIRY_SWEEP_INTERVAL", "0s")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for zero EXPIRY_SWEEP_INTERVAL")
+	}
+}
+
 func TestLoadConfigDefaultMaxKeys(t *testing.T) {
 	t.Setenv("PORT", "")
 	t.Setenv("CACHE_TTL", "")
@@ -83,6 +107,51 @@ func TestLoadConfigMaxKeysFromEnv(t *testing.T) {
 	}
 }
 
+func TestLoadConfigEvictionPolicy(t *testing.T) {
+	t.Setenv("EVICTION_POLICY", "sieve")
+
+	cfg, err := LoadConfig()
+
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.EvictionPolicy != "sieve" {
+		t.Errorf("expected eviction policy sieve, got %s", cfg.EvictionPolicy)
+	}
+	if _, ok := cfg.EvictorFactory().(*evictors.SieveEvictor); !ok {
+		t.Errorf("expected factory to create a SieveEvictor, got %T", cfg.EvictorFactory())
+	}
+}
+
+func TestLoadConfigUnknownEvictionPolicy(t *testing.T) {
+	t.Setenv("EVICTION_POLICY", "mru")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for unknown EVICTION_POLICY")
+	}
+}
+
+func TestLoadConfigSizeAccounting(t *testing.T) {
+	t.Setenv("SIZE_ACCOUNTING", "heap")
+
+	cfg, err := LoadConfig()
+
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.SizeAccounting != cache.AccountHeap {
+		t.Errorf("expected heap size accounting, got %s", cfg.SizeAccounting)
+	}
+}
+
+func TestLoadConfigUnknownSizeAccounting(t *testing.T) {
+	t.Setenv("SIZE_ACCOUNTING", "rss")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for unknown SIZE_ACCOUNTING")
+	}
+}
+
 func TestLoadConfigInvalidEnv(t *testing.T) {
 	t.Setenv("PORT", "notnum")
 
@@ -90,3 +159,42 @@ func TestLoadConfigInvalidEnv(t *testing.T) {
 		t.Fatalf("expected error for invalid PORT")
 	}
 }
+
+func TestLoadConfigMetricsExporter(t *testing.T) {
+	t.Setenv("METRICS_EXPORTER", "none")
+
+	cfg, err := LoadConfig()
+
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.MetricsExporter != "none" {
+		t.Errorf("expected metrics exporter none, got %s", cfg.MetricsExporter)
+	}
+
+	t.Setenv("METRICS_EXPORTER", "statsd")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for unknown METRICS_EXPORTER")
+	}
+}
+
+func TestLoadConfigTracesExporter(t *testing.T) {
+	cfg, err := LoadConfig()
+
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.TracesExporter != "none" {
+		t.Errorf("expected traces to be off by default, got %s", cfg.TracesExporter)
+	}
+
+	t.Setenv("TRACES_EXPORTER", "otlp")
+	if cfg, err = LoadConfig(); err != nil || cfg.TracesExporter != "otlp" {
+		t.Fatalf("expected otlp traces exporter, got %v %v", cfg, err)
+	}
+
+	t.Setenv("TRACES_EXPORTER", "prometheus")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for a TRACES_EXPORTER that cannot export traces")
+	}
+}
@@ -0,0 +1,196 @@
+package evictors
+
+import (
+	"container/list"
+	"sync"
+)
+
+type arcList uint8
+
+const (
+	arcT1 arcList = iota // resident keys seen once recently
+	arcT2                // resident keys seen at least twice recently
+	arcB1                // ghosts of keys evicted from T1
+	arcB2                // ghosts of keys evicted from T2
+)
+
+type arcEntry struct {
+	key  string
+	list arcList
+}
+
+// ARCEvictor implements the Adaptive Replacement Cache policy by Megiddo and Modha.
+// Resident keys are split into T1 (seen once) and T2 (seen more than once), and the
+// ghost lists B1 and B2 remember keys recently evicted from each of them. A miss on a ghost
+// moves the target size p of T1: B1 hits mean recency was undervalued, B2 hits mean frequency was.
+//
+// The ghost lists are bounded by the capacity passed to SetCapacity, which the cache sets
+// to the shard's fair share of the keys. A shard may hold more keys than that while the other shards
+// have room, so the capacity grows to the most keys the evictor has held at once, otherwise the ghosts
+// of the busiest shards would be trimmed to nothing and ARC would stop adapting where it matters most.
+// Until SetCapacity is called only that peak is used.
+type ARCEvictor struct {
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	lists    [4]*list.List
+	capacity int
+	peak     int     // the most resident keys held at once
+	p        float64 // target size of T1
+}
+
+// NewARCEvictor creates a new ARC eviction policy instance.
+func NewARCEvictor() *ARCEvictor {
+	a := &ARCEvictor{items: make(map[string]*list.Element)}
+	for i := range a.lists {
+		a.lists[i] = list.New()
+	}
+	return a
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (a *ARCEvictor) EntryOverhead() int64 {
+	return arcEntryOverhead
+}
+
+// SetCapacity sets the number of keys the cache can hold, which bounds the ghost lists.
+func (a *ARCEvictor) SetCapacity(keys int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.capacity = keys
+	a.p = min(a.p, float64(keys))
+	a.trimGhostsLocked()
+}
+
+func (a *ARCEvictor) OnSet(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	el, ok := a.items[key]
+	if !ok {
+		a.items[key] = a.lists[arcT1].PushFront(&arcEntry{key: key, list: arcT1})
+		a.peak = max(a.peak, a.lists[arcT1].Len()+a.lists[arcT2].Len())
+		a.trimGhostsLocked()
+		return
+	}
+
+	e := el.Value.(*arcEntry)
+	switch e.list {
+	case arcB1:
+		// The key was evicted from T1 too early, give recency more room
+		delta := max(float64(a.lists[arcB2].Len())/float64(a.lists[arcB1].Len()), 1)
+		a.p = min(a.p+delta, float64(a.capacityLocked()))
+	case arcB2:
+		// The key was evicted from T2 too early, give frequency more room
+		delta := max(float64(a.lists[arcB1].Len())/float64(a.lists[arcB2].Len()), 1)
+		a.p = max(a.p-delta, 0)
+	}
+
+	a.moveLocked(el, arcT2)
+	a.peak = max(a.peak, a.lists[arcT1].Len()+a.lists[arcT2].Len())
+	a.trimGhostsLocked()
+}
+
+func (a *ARCEvictor) OnGet(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	el, ok := a.items[key]
+	if !ok {
+		return
+	}
+
+	// Only resident keys can be read, ghosts are handled when the key is set again
+	if current := el.Value.(*arcEntry).list; current == arcT1 || current == arcT2 {
+		a.moveLocked(el, arcT2)
+	}
+}
+
+func (a *ARCEvictor) OnDelete(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if el, ok := a.items[key]; ok {
+		a.lists[el.Value.(*arcEntry).list].Remove(el)
+		delete(a.items, key)
+	}
+}
+
+// Evict returns count keys chosen by the ARC policy and remembers them as ghosts.
+// If no keys are available to evict, it returns nil.
+func (a *ARCEvictor) Evict(count int) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lists[arcT1].Len()+a.lists[arcT2].Len() == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		el := a.replaceLocked()
+		if el == nil {
+			break
+		}
+		keysToEvict = append(keysToEvict, el.Value.(*arcEntry).key)
+	}
+
+	a.trimGhostsLocked()
+	return keysToEvict
+}
+
+// replaceLocked moves the LRU key of T1 or T2 to its ghost list, depending on whether T1 is above its target
+func (a *ARCEvictor) replaceLocked() *list.Element {
+	t1, t2 := a.lists[arcT1], a.lists[arcT2]
+	if t1.Len()+t2.Len() == 0 {
+		return nil
+	}
+
+	// p is a target for a full cache, scale it when the shard holds fewer keys than its capacity
+	target := a.p * float64(t1.Len()+t2.Len()) / float64(a.capacityLocked())
+
+	if t1.Len() > 0 && (float64(t1.Len()) > target || t2.Len() == 0) {
+		return a.moveLocked(t1.Back(), arcB1)
+	}
+	return a.moveLocked(t2.Back(), arcB2)
+}
+
+// trimGhostsLocked drops the oldest ghosts so that T1 and B1 together and both ghost lists
+// together never hold more keys than the capacity
+func (a *ARCEvictor) trimGhostsLocked() {
+	capacity := a.capacityLocked()
+	t1, b1, b2 := a.lists[arcT1], a.lists[arcB1], a.lists[arcB2]
+
+	for b1.Len() > 0 && t1.Len()+b1.Len() > capacity {
+		a.dropLocked(b1.Back())
+	}
+
+	for b1.Len()+b2.Len() > capacity {
+		if b2.Len() > 0 {
+			a.dropLocked(b2.Back())
+		} else {
+			a.dropLocked(b1.Back())
+		}
+	}
+}
+
+func (a *ARCEvictor) moveLocked(el *list.Element, to arcList) *list.Element {
+	e := el.Value.(*arcEntry)
+	a.lists[e.list].Remove(el)
+	e.list = to
+
+	moved := a.lists[to].PushFront(e)
+	a.items[e.key] = moved
+	return moved
+}
+
+func (a *ARCEvictor) dropLocked(el *list.Element) {
+	e := el.Value.(*arcEntry)
+	a.lists[e.list].Remove(el)
+	delete(a.items, e.key)
+}
+
+// capacityLocked returns the capacity set through SetCapacity, or the most keys held at once if that is larger
+func (a *ARCEvictor) capacityLocked() int {
+	return max(a.capacity, a.peak, 1)
+}
@@ -0,0 +1,155 @@
+package evictors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestARCEvictor(t *testing.T) {
+	arcEvictor := NewARCEvictor()
+
+	arcEvictor.OnSet("a")
+	arcEvictor.OnSet("b")
+	arcEvictor.OnGet("a")
+	arcEvictor.OnSet("c")
+
+	itemsToBeEvicted := arcEvictor.Evict(1)
+
+	if itemsToBeEvicted == nil || len(itemsToBeEvicted) != 1 || itemsToBeEvicted[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", itemsToBeEvicted)
+	}
+
+	arcEvictor.OnDelete("a")
+
+	if e := arcEvictor.Evict(1); len(e) == 0 || e[0] != "c" {
+		t.Fatalf("expected c to be evicted, got %v", e)
+	}
+}
+
+func TestARCEvictorEmpty(t *testing.T) {
+	arcEvictor := NewARCEvictor()
+
+	if itemsToEvict := arcEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
+
+func TestARCEvictorAdaptsTarget(t *testing.T) {
+	arcEvictor := NewARCEvictor()
+	arcEvictor.SetCapacity(4)
+
+	// a and b are used twice and live in T2, c and d are used once and live in T1
+	arcEvictor.OnSet("a")
+	arcEvictor.OnGet("a")
+	arcEvictor.OnSet("b")
+	arcEvictor.OnGet("b")
+	arcEvictor.OnSet("c")
+	arcEvictor.OnSet("d")
+
+	// e makes room by evicting c from T1, and c comes back right after
+	arcEvictor.Evict(1)
+	arcEvictor.OnSet("e")
+	arcEvictor.Evict(1)
+	arcEvictor.OnSet("c")
+
+	if arcEvictor.p != 1 {
+		t.Fatalf("expected a hit in B1 to grow the T1 target to 1, got %v", arcEvictor.p)
+	}
+
+	// Now keys evicted from T2 come back, so frequency was undervalued
+	arcEvictor.Evict(1)
+	arcEvictor.OnSet("f")
+	arcEvictor.Evict(1)
+	arcEvictor.OnSet("a")
+
+	if arcEvictor.p != 0 {
+		t.Fatalf("expected a hit in B2 to shrink the T1 target to 0, got %v", arcEvictor.p)
+	}
+}
+
+func TestARCEvictorAdaptsToShiftingPattern(t *testing.T) {
+	const capacity = 100
+	arcEvictor := NewARCEvictor()
+	arcEvictor.SetCapacity(capacity)
+
+	sim := newSimulation(arcEvictor, capacity)
+
+	// A frequency heavy phase leaves most of the cache to T2
+	sim.run(zipfTrace(3, 1.3, 1_000, 50_000))
+	afterFrequencyPhase := arcEvictor.p
+
+	// A recency heavy phase loops over a working set that is slightly larger than what T1 holds,
+	// keys come back shortly after being evicted from T1 and the target of T1 grows
+	loop := make([]string, 0, 40_000)
+	for i := range 40_000 {
+		loop = append(loop, fmt.Sprintf("loop-%d", i%60))
+	}
+	sim.run(loop)
+	afterRecencyPhase := arcEvictor.p
+
+	t.Logf("T1 target: after frequency phase=%.1f after recency phase=%.1f", afterFrequencyPhase, afterRecencyPhase)
+
+	if afterRecencyPhase <= afterFrequencyPhase {
+		t.Fatalf("expected T1 target to grow when the pattern shifts to recency, %.1f -> %.1f", afterFrequencyPhase, afterRecencyPhase)
+	}
+}
+
+func TestARCEvictorBoundsGhosts(t *testing.T) {
+	const capacity = 50
+	arcEvictor := NewARCEvictor()
+	arcEvictor.SetCapacity(capacity)
+
+	sim := newSimulation(arcEvictor, capacity)
+	sim.run(zipfTrace(4, 1.1, 5_000, 20_000))
+	sim.run(scanTrace("scan", 10_000))
+
+	ghosts := arcEvictor.lists[arcB1].Len() + arcEvictor.lists[arcB2].Len()
+	if ghosts > capacity {
+		t.Fatalf("expected at most %d ghost entries, got %d", capacity, ghosts)
+	}
+	if len(arcEvictor.items) > 2*capacity {
+		t.Fatalf("expected at most %d tracked keys, got %d", 2*capacity, len(arcEvictor.items))
+	}
+}
+
+func TestARCEvictorKeepsGhostsPastCapacity(t *testing.T) {
+	// The shard's fair share is 10 keys, but it holds 100 while other shards have room
+	arcEvictor := NewARCEvictor()
+	arcEvictor.SetCapacity(10)
+	for i := range 100 {
+		arcEvictor.OnSet(fmt.Sprintf("k%d", i))
+	}
+
+	evicted := arcEvictor.Evict(20)
+	if ghosts := arcEvictor.lists[arcB1].Len(); ghosts != len(evicted) {
+		t.Fatalf("expected the %d evicted keys to be kept as ghosts, got %d", len(evicted), ghosts)
+	}
+
+	// A ghost hit still moves the target of T1
+	arcEvictor.OnSet(evicted[0])
+	if arcEvictor.p == 0 {
+		t.Fatalf("expected a B1 ghost hit to grow the target of T1")
+	}
+}
+
+func TestARCHitRatioZipfWithScans(t *testing.T) {
+	zipf := zipfTrace(5, 1.1, 10_000, 200_000)
+
+	trace := make([]string, 0, len(zipf)*2)
+	for i := 0; i < len(zipf); i += 20_000 {
+		trace = append(trace, zipf[i:i+20_000]...)
+		trace = append(trace, scanTrace("scan", 5_000)...)
+	}
+
+	arcEvictor := NewARCEvictor()
+	arcEvictor.SetCapacity(500)
+
+	lru := hitRatio(NewLRUEvictor(), 500, trace)
+	arc := hitRatio(arcEvictor, 500, trace)
+
+	t.Logf("zipf with scans hit ratio: lru=%.3f arc=%.3f", lru, arc)
+
+	if arc <= lru {
+		t.Fatalf("expected ARC to beat LRU when scans are mixed in, lru=%.3f arc=%.3f", lru, arc)
+	}
+}
@@ -0,0 +1,89 @@
+package evictors
+
+import (
+	"fmt"
+	"testing"
+)
+
+var benchmarkEvictors = []struct {
+	name    string
+	factory func() Evictor
+}{
+	{"lru", func() Evictor { return NewLRUEvictor() }},
+	{"lfu", func() Evictor { return NewLFUEvictor() }},
+	{"fifo", func() Evictor { return NewFIFOEvictor() }},
+	{"random", func() Evictor { return NewRandomEvictor() }},
+	{"tinylfu", func() Evictor { return NewTinyLFUEvictor() }},
+	{"arc", func() Evictor { return NewARCEvictor() }},
+	{"sieve", func() Evictor { return NewSieveEvictor() }},
+	{"clock", func() Evictor { return NewClockEvictor() }},
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
+
+func BenchmarkEvictorSet(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+
+	for _, bm := range benchmarkEvictors {
+		b.Run(bm.name, func(b *testing.B) {
+			evictor := bm.factory()
+
+			for i := 0; b.Loop(); i++ {
+				evictor.OnSet(keys[i%len(keys)])
+			}
+		})
+	}
+}
+
+// BenchmarkEvictorParallelGet measures the cost of recording a hit, which is paid on every cache read
+func BenchmarkEvictorParallelGet(b *testing.B) {
+	keys := benchmarkKeys(1024)
+
+	for _, bm := range benchmarkEvictors {
+		b.Run(bm.name, func(b *testing.B) {
+			evictor := bm.factory()
+			for _, key := range keys {
+				evictor.OnSet(key)
+			}
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					evictor.OnGet(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkEvictorEvict(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+
+	for _, bm := range benchmarkEvictors {
+		b.Run(bm.name, func(b *testing.B) {
+			evictor := bm.factory()
+			for _, key := range keys {
+				evictor.OnSet(key)
+			}
+
+			b.ResetTimer()
+
+			for i := 0; b.Loop(); i++ {
+				// Keep the evictor full by adding back every key it evicts
+				for _, key := range evictor.Evict(1) {
+					evictor.OnSet(key)
+				}
+				evictor.OnGet(keys[i%len(keys)])
+			}
+		})
+	}
+}
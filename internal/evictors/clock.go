@@ -0,0 +1,118 @@
+package evictors
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type clockEntry struct {
+	key     string
+	slot    int
+	visited atomic.Bool
+}
+
+// ClockEvictor implements the CLOCK policy, an approximation of LRU.
+// Keys sit in a circular buffer and a hit only marks the key as visited. Eviction
+// sweeps a hand around the buffer, clearing visited bits and evicting the first key
+// that was not visited since the hand last passed it. Freed slots are reused by new keys.
+//
+// Like SieveEvictor, OnGet does not take the evictor's lock.
+type ClockEvictor struct {
+	mu    sync.Mutex
+	index sync.Map // key -> *clockEntry
+	slots []*clockEntry
+	free  []int
+	hand  int
+	size  int
+}
+
+// NewClockEvictor creates a new CLOCK eviction policy instance.
+func NewClockEvictor() *ClockEvictor {
+	return &ClockEvictor{}
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (c *ClockEvictor) EntryOverhead() int64 {
+	return clockEntryOverhead
+}
+
+func (c *ClockEvictor) OnSet(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.index.Load(key); ok {
+		e.(*clockEntry).visited.Store(true)
+		return
+	}
+
+	e := &clockEntry{key: key}
+	if n := len(c.free); n > 0 {
+		e.slot = c.free[n-1]
+		c.free = c.free[:n-1]
+		c.slots[e.slot] = e
+	} else {
+		e.slot = len(c.slots)
+		c.slots = append(c.slots, e)
+	}
+
+	c.index.Store(key, e)
+	c.size++
+}
+
+func (c *ClockEvictor) OnGet(key string) {
+	if e, ok := c.index.Load(key); ok {
+		e.(*clockEntry).visited.Store(true)
+	}
+}
+
+// LockFreeOnGet marks OnGet as safe to call without batching, see LockFreeReader.
+func (c *ClockEvictor) LockFreeOnGet() {}
+
+func (c *ClockEvictor) OnDelete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.index.Load(key); ok {
+		c.removeLocked(e.(*clockEntry))
+	}
+}
+
+// Evict returns the first count keys the hand finds unvisited.
+// If no keys are available to evict, it returns nil.
+func (c *ClockEvictor) Evict(count int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count && c.size > 0; i++ {
+		for {
+			e := c.slots[c.hand]
+			c.hand = (c.hand + 1) % len(c.slots)
+
+			if e == nil {
+				continue
+			}
+			if e.visited.Load() {
+				e.visited.Store(false)
+				continue
+			}
+
+			c.removeLocked(e)
+			keysToEvict = append(keysToEvict, e.key)
+			break
+		}
+	}
+
+	return keysToEvict
+}
+
+func (c *ClockEvictor) removeLocked(e *clockEntry) {
+	c.slots[e.slot] = nil
+	c.free = append(c.free, e.slot)
+	c.index.Delete(e.key)
+	c.size--
+}
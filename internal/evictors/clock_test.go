@@ -0,0 +1,89 @@
+package evictors
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestClockEvictor(t *testing.T) {
+	clockEvictor := NewClockEvictor()
+
+	clockEvictor.OnSet("a")
+	clockEvictor.OnSet("b")
+	clockEvictor.OnGet("a")
+	clockEvictor.OnSet("c")
+
+	itemsToBeEvicted := clockEvictor.Evict(1)
+
+	if itemsToBeEvicted == nil || len(itemsToBeEvicted) != 1 || itemsToBeEvicted[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", itemsToBeEvicted)
+	}
+
+	clockEvictor.OnDelete("a")
+
+	if e := clockEvictor.Evict(1); len(e) == 0 || e[0] != "c" {
+		t.Fatalf("expected c to be evicted, got %v", e)
+	}
+}
+
+func TestClockEvictorEmpty(t *testing.T) {
+	clockEvictor := NewClockEvictor()
+
+	if itemsToEvict := clockEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
+
+func TestClockEvictorAllVisited(t *testing.T) {
+	clockEvictor := NewClockEvictor()
+
+	clockEvictor.OnSet("a")
+	clockEvictor.OnSet("b")
+	clockEvictor.OnGet("a")
+	clockEvictor.OnGet("b")
+
+	// With every key visited the hand clears all bits and comes back to the oldest key
+	if e := clockEvictor.Evict(2); len(e) != 2 || e[0] != "a" || e[1] != "b" {
+		t.Fatalf("expected a and b to be evicted in order, got %v", e)
+	}
+	if e := clockEvictor.Evict(1); len(e) != 0 {
+		t.Fatalf("expected nothing left to evict, got %v", e)
+	}
+}
+
+func TestClockEvictorConcurrentGet(t *testing.T) {
+	clockEvictor := NewClockEvictor()
+
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 5_000 {
+				clockEvictor.OnGet(fmt.Sprintf("key-%d", (g+i)%100))
+			}
+		}()
+	}
+
+	for i := range 5_000 {
+		clockEvictor.OnSet(fmt.Sprintf("key-%d", i%100))
+		if i%10 == 0 {
+			clockEvictor.Evict(1)
+		}
+	}
+	wg.Wait()
+}
+
+func TestClockHitRatioCloseToLRU(t *testing.T) {
+	trace := zipfTrace(6, 1.1, 10_000, 200_000)
+
+	lru := hitRatio(NewLRUEvictor(), 500, trace)
+	clock := hitRatio(NewClockEvictor(), 500, trace)
+
+	t.Logf("zipf hit ratio: lru=%.3f clock=%.3f", lru, clock)
+
+	if clock < lru*0.95 {
+		t.Fatalf("expected Clock to be within 5%% of LRU, lru=%.3f clock=%.3f", lru, clock)
+	}
+}
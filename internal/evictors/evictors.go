@@ -10,3 +10,43 @@ type Evictor interface {
 
 	Evict(count int) []string
 }
+
+// Admitter can be implemented by an Evictor that decides whether a new key is worth storing.
+// The cache only asks when storing a key that is not cached yet requires evicting other keys,
+// a rejected key is not stored.
+type Admitter interface {
+	Admit(key string) bool
+}
+
+// CapacityAware can be implemented by an Evictor that needs to know how many keys the cache can hold,
+// for example to bound the history it keeps about evicted keys.
+type CapacityAware interface {
+	SetCapacity(keys int)
+}
+
+// LockFreeReader can be implemented by an Evictor whose OnGet does not take a lock and is safe to call
+// from many goroutines at once. The cache then calls OnGet on every hit instead of batching hits in its
+// read buffer, which only pays off for evictors that serialize hits on a mutex.
+type LockFreeReader interface {
+	LockFreeOnGet()
+}
+
+// EntrySizer can be implemented by an Evictor to report how much heap it uses for every key it tracks,
+// so the cache can charge it to the entry when it bounds its estimated heap.
+type EntrySizer interface {
+	EntryOverhead() int64
+}
+
+// Heap used per tracked key by each policy, not counting the key's bytes which the cache stores once.
+// Measured with runtime.MemStats after filling a cache with 50k keys, see TestHeapAccountingMatchesMemStats
+// in the cache package. Map buckets are amortized over the keys, so the figures are averages.
+const (
+	lruEntryOverhead     = 105 // map entry, list element and the entry it points to
+	fifoEntryOverhead    = 105 // map entry, list element and the entry it points to
+	lfuEntryOverhead     = 121 // map entry, list element and lfuEntry, buckets are shared by many keys
+	arcEntryOverhead     = 110 // map entry, list element and arcEntry, ghosts of evicted keys come on top
+	tinyLFUEntryOverhead = 120 // map entry, list element and tinyLFUEntry, the sketch is amortized over the keys
+	randomEntryOverhead  = 63  // map entry and slot in the key slice
+	sieveEntryOverhead   = 166 // sync.Map entry and sieveNode
+	clockEntryOverhead   = 166 // sync.Map entry, clockEntry and its slot
+)
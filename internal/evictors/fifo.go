@@ -0,0 +1,76 @@
+package evictors
+
+import (
+	"container/list"
+	"sync"
+)
+
+// FIFOEvictor evicts keys in the order they were first stored, reads and updates do not change the order.
+type FIFOEvictor struct {
+	mu    sync.Mutex
+	items map[string]*list.Element
+	list  *list.List
+}
+
+// NewFIFOEvictor creates a new FIFO eviction policy instance.
+func NewFIFOEvictor() *FIFOEvictor {
+	return &FIFOEvictor{
+		items: make(map[string]*list.Element),
+		list:  list.New(),
+	}
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (f *FIFOEvictor) EntryOverhead() int64 {
+	return fifoEntryOverhead
+}
+
+func (f *FIFOEvictor) OnSet(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.items[key]; ok {
+		return
+	}
+
+	f.items[key] = f.list.PushFront(&entry{key: key})
+}
+
+func (f *FIFOEvictor) OnGet(string) {}
+
+func (f *FIFOEvictor) OnDelete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if el, ok := f.items[key]; ok {
+		f.list.Remove(el)
+		delete(f.items, key)
+	}
+}
+
+// Evict returns the n oldest keys, where n is passed as count.
+// If no keys are available to evict, it returns nil.
+func (f *FIFOEvictor) Evict(count int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.list.Len() == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		back := f.list.Back()
+		if back == nil {
+			break
+		}
+
+		f.list.Remove(back)
+		e := back.Value.(*entry)
+		delete(f.items, e.key)
+
+		keysToEvict = append(keysToEvict, e.key)
+	}
+
+	return keysToEvict
+}
@@ -0,0 +1,33 @@
+package evictors
+
+import "testing"
+
+func TestFIFOEvictor(t *testing.T) {
+	fifoEvictor := NewFIFOEvictor()
+
+	fifoEvictor.OnSet("a")
+	fifoEvictor.OnSet("b")
+	fifoEvictor.OnGet("a")
+	fifoEvictor.OnSet("a")
+	fifoEvictor.OnSet("c")
+
+	itemsToBeEvicted := fifoEvictor.Evict(1)
+
+	if itemsToBeEvicted == nil || len(itemsToBeEvicted) != 1 || itemsToBeEvicted[0] != "a" {
+		t.Fatalf("expected a to be evicted, got %v", itemsToBeEvicted)
+	}
+
+	fifoEvictor.OnDelete("b")
+
+	if e := fifoEvictor.Evict(1); len(e) == 0 || e[0] != "c" {
+		t.Fatalf("expected c to be evicted")
+	}
+}
+
+func TestFIFOEvictorEmpty(t *testing.T) {
+	fifoEvictor := NewFIFOEvictor()
+
+	if itemsToEvict := fifoEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
@@ -0,0 +1,190 @@
+package evictors
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lfuBucket holds all keys that have been accessed freq times,
+// the front of entries is the most recently used key in the bucket.
+type lfuBucket struct {
+	freq    int
+	entries *list.List
+}
+
+type lfuEntry struct {
+	key     string
+	bucket  *list.Element // element of LFUEvictor.buckets
+	element *list.Element // element of lfuBucket.entries
+}
+
+// LFUEvictor evicts the least frequently used keys, ties are broken by evicting the least recently used one.
+// All operations are O(1): keys live in buckets per access count and the buckets are kept
+// in a list ordered by ascending frequency, so the next victim is always in the first bucket.
+//
+// With aging enabled all frequencies are halved after a fixed number of accesses,
+// so keys that were hot a long time ago do not stay in the cache forever.
+type LFUEvictor struct {
+	mu            sync.Mutex
+	items         map[string]*lfuEntry
+	buckets       *list.List
+	agingInterval int
+	accesses      int
+}
+
+// NewLFUEvictor creates a new LFU eviction policy instance without aging.
+func NewLFUEvictor() *LFUEvictor {
+	return NewLFUEvictorWithAging(0)
+}
+
+// NewLFUEvictorWithAging creates a new LFU eviction policy instance that halves the
+// frequency of every key after each interval accesses. An interval of 0 disables aging.
+func NewLFUEvictorWithAging(interval int) *LFUEvictor {
+	if interval < 0 {
+		interval = 0
+	}
+
+	return &LFUEvictor{
+		items:         make(map[string]*lfuEntry),
+		buckets:       list.New(),
+		agingInterval: interval,
+	}
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (l *LFUEvictor) EntryOverhead() int64 {
+	return lfuEntryOverhead
+}
+
+func (l *LFUEvictor) OnSet(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		l.incrementLocked(e)
+	} else {
+		l.insertLocked(key)
+	}
+
+	l.recordAccessLocked()
+}
+
+func (l *LFUEvictor) OnGet(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		l.incrementLocked(e)
+		l.recordAccessLocked()
+	}
+}
+
+func (l *LFUEvictor) OnDelete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		l.unlinkLocked(e)
+		delete(l.items, key)
+	}
+}
+
+// Evict returns the n least frequently used keys,
+// where n is passed as count.
+// If no keys are available to evict, it returns nil.
+func (l *LFUEvictor) Evict(count int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.items) == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		front := l.buckets.Front()
+		if front == nil {
+			break
+		}
+
+		bucket := front.Value.(*lfuBucket)
+		e := bucket.entries.Back().Value.(*lfuEntry)
+		l.unlinkLocked(e)
+		delete(l.items, e.key)
+
+		keysToEvict = append(keysToEvict, e.key)
+	}
+
+	return keysToEvict
+}
+
+func (l *LFUEvictor) insertLocked(key string) {
+	first := l.buckets.Front()
+	if first == nil || first.Value.(*lfuBucket).freq != 1 {
+		first = l.buckets.PushFront(&lfuBucket{freq: 1, entries: list.New()})
+	}
+
+	e := &lfuEntry{key: key, bucket: first}
+	e.element = first.Value.(*lfuBucket).entries.PushFront(e)
+	l.items[key] = e
+}
+
+// incrementLocked moves the entry to the bucket for the next frequency
+func (l *LFUEvictor) incrementLocked(e *lfuEntry) {
+	current := e.bucket
+	freq := current.Value.(*lfuBucket).freq + 1
+
+	next := current.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != freq {
+		next = l.buckets.InsertAfter(&lfuBucket{freq: freq, entries: list.New()}, current)
+	}
+
+	l.unlinkLocked(e)
+	e.bucket = next
+	e.element = next.Value.(*lfuBucket).entries.PushFront(e)
+}
+
+// unlinkLocked removes the entry from its bucket and drops the bucket once it is empty
+func (l *LFUEvictor) unlinkLocked(e *lfuEntry) {
+	bucket := e.bucket.Value.(*lfuBucket)
+	bucket.entries.Remove(e.element)
+
+	if bucket.entries.Len() == 0 {
+		l.buckets.Remove(e.bucket)
+	}
+}
+
+func (l *LFUEvictor) recordAccessLocked() {
+	if l.agingInterval == 0 {
+		return
+	}
+
+	l.accesses++
+	if l.accesses >= l.agingInterval {
+		l.accesses = 0
+		l.ageLocked()
+	}
+}
+
+// ageLocked halves the frequency of every key. Buckets are visited in ascending order and
+// their keys oldest first, so the relative order of keys is kept and the new buckets stay sorted.
+func (l *LFUEvictor) ageLocked() {
+	old := l.buckets
+	l.buckets = list.New()
+
+	for b := old.Front(); b != nil; b = b.Next() {
+		bucket := b.Value.(*lfuBucket)
+		freq := max(1, bucket.freq/2)
+
+		target := l.buckets.Back()
+		if target == nil || target.Value.(*lfuBucket).freq != freq {
+			target = l.buckets.PushBack(&lfuBucket{freq: freq, entries: list.New()})
+		}
+
+		for el := bucket.entries.Back(); el != nil; el = el.Prev() {
+			e := el.Value.(*lfuEntry)
+			e.bucket = target
+			e.element = target.Value.(*lfuBucket).entries.PushFront(e)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package evictors
+
+import "testing"
+
+func TestLFUEvictor(t *testing.T) {
+	lfuEvictor := NewLFUEvictor()
+
+	lfuEvictor.OnSet("a")
+	lfuEvictor.OnSet("b")
+	lfuEvictor.OnGet("a")
+	lfuEvictor.OnSet("c")
+	lfuEvictor.OnGet("c")
+	lfuEvictor.OnGet("c")
+
+	itemsToBeEvicted := lfuEvictor.Evict(1)
+
+	if itemsToBeEvicted == nil || len(itemsToBeEvicted) != 1 || itemsToBeEvicted[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", itemsToBeEvicted)
+	}
+
+	lfuEvictor.OnDelete("a")
+
+	if e := lfuEvictor.Evict(1); len(e) == 0 || e[0] != "c" {
+		t.Fatalf("expected c to be evicted")
+	}
+}
+
+func TestLFUEvictorEmpty(t *testing.T) {
+	lfuEvictor := NewLFUEvictor()
+
+	if itemsToEvict := lfuEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
+
+func TestLFUEvictorTieBreaksByRecency(t *testing.T) {
+	lfuEvictor := NewLFUEvictor()
+
+	lfuEvictor.OnSet("a")
+	lfuEvictor.OnSet("b")
+	lfuEvictor.OnSet("c")
+
+	itemsToBeEvicted := lfuEvictor.Evict(2)
+
+	if len(itemsToBeEvicted) != 2 || itemsToBeEvicted[0] != "a" || itemsToBeEvicted[1] != "b" {
+		t.Fatalf("expected a and b to be evicted, got %v", itemsToBeEvicted)
+	}
+}
+
+func TestLFUEvictorResistsScans(t *testing.T) {
+	lfuEvictor := NewLFUEvictor()
+
+	lfuEvictor.OnSet("hot")
+	for range 10 {
+		lfuEvictor.OnGet("hot")
+	}
+
+	// A burst of one-off keys must not push out the hot key
+	for _, key := range []string{"s1", "s2", "s3", "s4"} {
+		lfuEvictor.OnSet(key)
+	}
+
+	itemsToBeEvicted := lfuEvictor.Evict(4)
+	for _, key := range itemsToBeEvicted {
+		if key == "hot" {
+			t.Fatalf("expected hot key to survive the scan, got %v", itemsToBeEvicted)
+		}
+	}
+}
+
+func TestLFUEvictorAging(t *testing.T) {
+	access := func(e *LFUEvictor) {
+		// old is used 12 times a long time ago, new is used 6 times recently
+		e.OnSet("old")
+		for range 11 {
+			e.OnGet("old")
+		}
+		e.OnSet("new")
+		for range 5 {
+			e.OnGet("new")
+		}
+	}
+
+	withoutAging := NewLFUEvictor()
+	access(withoutAging)
+
+	if e := withoutAging.Evict(1); len(e) != 1 || e[0] != "new" {
+		t.Fatalf("expected new to be evicted without aging, got %v", e)
+	}
+
+	// Halving every 6 accesses leaves old at 2 and new at 3
+	withAging := NewLFUEvictorWithAging(6)
+	access(withAging)
+
+	if e := withAging.Evict(1); len(e) != 1 || e[0] != "old" {
+		t.Fatalf("expected aged key old to be evicted, got %v", e)
+	}
+}
@@ -23,6 +23,11 @@ func NewLRUEvictor() *LRUEvictor {
 	}
 }
 
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (l *LRUEvictor) EntryOverhead() int64 {
+	return lruEntryOverhead
+}
+
 func (l *LRUEvictor) OnSet(key string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
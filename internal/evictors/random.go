@@ -0,0 +1,79 @@
+package evictors
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// RandomEvictor evicts keys chosen uniformly at random.
+// It keeps no access history, which makes it the cheapest policy and a useful baseline.
+type RandomEvictor struct {
+	mu    sync.Mutex
+	index map[string]int // position of each key in keys
+	keys  []string
+}
+
+// NewRandomEvictor creates a new random eviction policy instance.
+func NewRandomEvictor() *RandomEvictor {
+	return &RandomEvictor{index: make(map[string]int)}
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (r *RandomEvictor) EntryOverhead() int64 {
+	return randomEntryOverhead
+}
+
+func (r *RandomEvictor) OnSet(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.index[key]; ok {
+		return
+	}
+
+	r.index[key] = len(r.keys)
+	r.keys = append(r.keys, key)
+}
+
+func (r *RandomEvictor) OnGet(string) {}
+
+func (r *RandomEvictor) OnDelete(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i, ok := r.index[key]; ok {
+		r.removeLocked(i)
+	}
+}
+
+// Evict returns n random keys, where n is passed as count.
+// If no keys are available to evict, it returns nil.
+func (r *RandomEvictor) Evict(count int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.keys) == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count && len(r.keys) > 0; i++ {
+		victim := rand.IntN(len(r.keys))
+		keysToEvict = append(keysToEvict, r.keys[victim])
+		r.removeLocked(victim)
+	}
+
+	return keysToEvict
+}
+
+// removeLocked swaps the key at i with the last key so removal is O(1)
+func (r *RandomEvictor) removeLocked(i int) {
+	last := len(r.keys) - 1
+	delete(r.index, r.keys[i])
+
+	if i != last {
+		r.keys[i] = r.keys[last]
+		r.index[r.keys[i]] = i
+	}
+	r.keys = r.keys[:last]
+}
@@ -0,0 +1,31 @@
+package evictors
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRandomEvictor(t *testing.T) {
+	randomEvictor := NewRandomEvictor()
+
+	randomEvictor.OnSet("a")
+	randomEvictor.OnSet("b")
+	randomEvictor.OnSet("c")
+	randomEvictor.OnSet("a")
+	randomEvictor.OnDelete("b")
+
+	itemsToBeEvicted := randomEvictor.Evict(3)
+	slices.Sort(itemsToBeEvicted)
+
+	if !slices.Equal(itemsToBeEvicted, []string{"a", "c"}) {
+		t.Fatalf("expected a and c to be evicted exactly once, got %v", itemsToBeEvicted)
+	}
+}
+
+func TestRandomEvictorEmpty(t *testing.T) {
+	randomEvictor := NewRandomEvictor()
+
+	if itemsToEvict := randomEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
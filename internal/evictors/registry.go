@@ -0,0 +1,63 @@
+package evictors
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Factory creates a new evictor, the cache calls it once for every shard.
+type Factory func() Evictor
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		"lru":     func() Evictor { return NewLRUEvictor() },
+		"lfu":     func() Evictor { return NewLFUEvictor() },
+		"fifo":    func() Evictor { return NewFIFOEvictor() },
+		"random":  func() Evictor { return NewRandomEvictor() },
+		"tinylfu": func() Evictor { return NewTinyLFUEvictor() },
+		"arc":     func() Evictor { return NewARCEvictor() },
+		"sieve":   func() Evictor { return NewSieveEvictor() },
+		"clock":   func() Evictor { return NewClockEvictor() },
+	}
+)
+
+// Register makes an eviction policy available under name, replacing any policy registered under the same name.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[strings.ToLower(name)] = factory
+}
+
+// Lookup returns the factory of the eviction policy registered under name, names are case insensitive.
+func Lookup(name string) (Factory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown eviction policy %q, expected one of %s", name, strings.Join(namesLocked(), ", "))
+	}
+
+	return factory, nil
+}
+
+// Names returns the names of all registered eviction policies in alphabetical order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,44 @@
+package evictors
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"lru", "lfu", "fifo", "random", "tinylfu", "arc", "sieve", "clock"} {
+		factory, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", name, err)
+		}
+		if factory() == nil {
+			t.Fatalf("expected factory for %s to create an evictor", name)
+		}
+	}
+
+	if _, err := Lookup("LRU"); err != nil {
+		t.Fatalf("expected lookup to be case insensitive, got %v", err)
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	if _, err := Lookup("mru"); err == nil {
+		t.Fatalf("expected error for unknown policy")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-policy", func() Evictor { return NewLRUEvictor() })
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "test-policy")
+		registryMu.Unlock()
+	})
+
+	if _, err := Lookup("test-policy"); err != nil {
+		t.Fatalf("expected registered policy to be found, got %v", err)
+	}
+	if !slices.Contains(Names(), "test-policy") {
+		t.Fatalf("expected registered policy in names, got %v", Names())
+	}
+}
@@ -0,0 +1,135 @@
+package evictors
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type sieveNode struct {
+	key        string
+	visited    atomic.Bool
+	prev, next *sieveNode
+}
+
+// SieveEvictor implements the SIEVE policy by Zhang et al.
+// Keys are kept in insertion order and a hit only marks the key as visited,
+// without moving it. Eviction walks a hand from the oldest key towards the newest,
+// clearing visited bits on the way, and evicts the first key that was not visited.
+//
+// OnGet does not take the evictor's lock, it looks the key up in a sync.Map and sets an atomic bit,
+// which makes hits much cheaper than with LRU while keeping a comparable hit ratio.
+type SieveEvictor struct {
+	mu    sync.Mutex
+	index sync.Map   // key -> *sieveNode
+	head  *sieveNode // newest key
+	tail  *sieveNode // oldest key
+	hand  *sieveNode
+	size  int
+}
+
+// NewSieveEvictor creates a new SIEVE eviction policy instance.
+func NewSieveEvictor() *SieveEvictor {
+	return &SieveEvictor{}
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (s *SieveEvictor) EntryOverhead() int64 {
+	return sieveEntryOverhead
+}
+
+func (s *SieveEvictor) OnSet(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if node, ok := s.index.Load(key); ok {
+		node.(*sieveNode).visited.Store(true)
+		return
+	}
+
+	node := &sieveNode{key: key, next: s.head}
+	if s.head != nil {
+		s.head.prev = node
+	}
+	s.head = node
+	if s.tail == nil {
+		s.tail = node
+	}
+
+	s.index.Store(key, node)
+	s.size++
+}
+
+func (s *SieveEvictor) OnGet(key string) {
+	if node, ok := s.index.Load(key); ok {
+		node.(*sieveNode).visited.Store(true)
+	}
+}
+
+// LockFreeOnGet marks OnGet as safe to call without batching, see LockFreeReader.
+func (s *SieveEvictor) LockFreeOnGet() {}
+
+func (s *SieveEvictor) OnDelete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if node, ok := s.index.Load(key); ok {
+		s.unlinkLocked(node.(*sieveNode))
+	}
+}
+
+// Evict returns the first count keys the hand finds unvisited.
+// If no keys are available to evict, it returns nil.
+func (s *SieveEvictor) Evict(count int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count && s.size > 0; i++ {
+		node := s.hand
+		if node == nil {
+			node = s.tail
+		}
+
+		// Every visited key gets a second chance, so this terminates after at most one full pass
+		for node.visited.Load() {
+			node.visited.Store(false)
+			node = node.prev
+			if node == nil {
+				node = s.tail
+			}
+		}
+
+		// The hand continues from the next newer key on the following eviction
+		s.hand = node
+		s.unlinkLocked(node)
+		keysToEvict = append(keysToEvict, node.key)
+	}
+
+	return keysToEvict
+}
+
+func (s *SieveEvictor) unlinkLocked(node *sieveNode) {
+	if s.hand == node {
+		s.hand = node.prev
+	}
+
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		s.head = node.next
+	}
+
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		s.tail = node.prev
+	}
+
+	node.prev, node.next = nil, nil
+	s.index.Delete(node.key)
+	s.size--
+}
@@ -0,0 +1,89 @@
+package evictors
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSieveEvictor(t *testing.T) {
+	sieveEvictor := NewSieveEvictor()
+
+	sieveEvictor.OnSet("a")
+	sieveEvictor.OnSet("b")
+	sieveEvictor.OnGet("a")
+	sieveEvictor.OnSet("c")
+
+	itemsToBeEvicted := sieveEvictor.Evict(1)
+
+	if itemsToBeEvicted == nil || len(itemsToBeEvicted) != 1 || itemsToBeEvicted[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", itemsToBeEvicted)
+	}
+
+	sieveEvictor.OnDelete("a")
+
+	if e := sieveEvictor.Evict(1); len(e) == 0 || e[0] != "c" {
+		t.Fatalf("expected c to be evicted, got %v", e)
+	}
+}
+
+func TestSieveEvictorEmpty(t *testing.T) {
+	sieveEvictor := NewSieveEvictor()
+
+	if itemsToEvict := sieveEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
+
+func TestSieveEvictorAllVisited(t *testing.T) {
+	sieveEvictor := NewSieveEvictor()
+
+	sieveEvictor.OnSet("a")
+	sieveEvictor.OnSet("b")
+	sieveEvictor.OnGet("a")
+	sieveEvictor.OnGet("b")
+
+	// With every key visited the hand clears all bits and comes back to the oldest key
+	if e := sieveEvictor.Evict(2); len(e) != 2 || e[0] != "a" || e[1] != "b" {
+		t.Fatalf("expected a and b to be evicted in order, got %v", e)
+	}
+	if e := sieveEvictor.Evict(1); len(e) != 0 {
+		t.Fatalf("expected nothing left to evict, got %v", e)
+	}
+}
+
+func TestSieveEvictorConcurrentGet(t *testing.T) {
+	sieveEvictor := NewSieveEvictor()
+
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 5_000 {
+				sieveEvictor.OnGet(fmt.Sprintf("key-%d", (g+i)%100))
+			}
+		}()
+	}
+
+	for i := range 5_000 {
+		sieveEvictor.OnSet(fmt.Sprintf("key-%d", i%100))
+		if i%10 == 0 {
+			sieveEvictor.Evict(1)
+		}
+	}
+	wg.Wait()
+}
+
+func TestSieveHitRatioCloseToLRU(t *testing.T) {
+	trace := zipfTrace(6, 1.1, 10_000, 200_000)
+
+	lru := hitRatio(NewLRUEvictor(), 500, trace)
+	sieve := hitRatio(NewSieveEvictor(), 500, trace)
+
+	t.Logf("zipf hit ratio: lru=%.3f sieve=%.3f", lru, sieve)
+
+	if sieve < lru*0.95 {
+		t.Fatalf("expected Sieve to be within 5%% of LRU, lru=%.3f sieve=%.3f", lru, sieve)
+	}
+}
@@ -0,0 +1,103 @@
+package evictors
+
+import "hash/maphash"
+
+const (
+	sketchDepth      = 4
+	sketchMaxCount   = 15 // counters saturate like the 4 bit counters used by TinyLFU
+	sketchMinWidth   = 64
+	sketchSampleRate = 10 // counters are halved after width * sketchSampleRate increments
+)
+
+// countMinSketch estimates how often a key has been seen using a fixed amount of memory.
+// Estimates can only be too high, never too low. All counters are halved periodically,
+// so the sketch reflects recent popularity rather than all time popularity.
+type countMinSketch struct {
+	seed       maphash.Seed
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+// newCountMinSketch creates a sketch with width counters per row, rounded up to a power of two.
+func newCountMinSketch(width int) *countMinSketch {
+	size := sketchMinWidth
+	for size < width {
+		size <<= 1
+	}
+
+	s := &countMinSketch{
+		seed:       maphash.MakeSeed(),
+		mask:       uint64(size - 1),
+		sampleSize: size * sketchSampleRate,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, size)
+	}
+
+	return s
+}
+
+func (s *countMinSketch) width() int {
+	return int(s.mask + 1)
+}
+
+// indexes derives one counter per row from a single 64 bit hash using double hashing
+func (s *countMinSketch) indexes(key string) [sketchDepth]uint64 {
+	h := maphash.String(s.seed, key)
+	h1, h2 := h&0xffffffff, h>>32
+
+	var idx [sketchDepth]uint64
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) & s.mask
+	}
+	return idx
+}
+
+func (s *countMinSketch) increment(key string) {
+	for row, i := range s.indexes(key) {
+		if s.rows[row][i] < sketchMaxCount {
+			s.rows[row][i]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(key string) uint8 {
+	estimate := uint8(sketchMaxCount)
+	for row, i := range s.indexes(key) {
+		estimate = min(estimate, s.rows[row][i])
+	}
+	return estimate
+}
+
+// grow doubles the width of the sketch without losing its counts.
+// The seed stays the same, so a key's counter in a row of the wider sketch is either at the same index
+// or at the same index plus the old width. Both start from the old counter, so every estimate is unchanged
+// and stays an upper bound, and the extra width only separates keys that are added from now on.
+func (s *countMinSketch) grow() {
+	width := s.width()
+	for row := range s.rows {
+		wider := make([]uint8, width*2)
+		copy(wider, s.rows[row])
+		copy(wider[width:], s.rows[row])
+		s.rows[row] = wider
+	}
+	s.mask = uint64(width*2 - 1)
+	s.sampleSize *= 2
+}
+
+// reset halves every counter so old accesses gradually lose their weight
+func (s *countMinSketch) reset() {
+	for row := range s.rows {
+		for i := range s.rows[row] {
+			s.rows[row][i] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,73 @@
+package evictors
+
+import "testing"
+
+func TestCountMinSketchEstimate(t *testing.T) {
+	sketch := newCountMinSketch(1024)
+
+	for range 5 {
+		sketch.increment("a")
+	}
+	sketch.increment("b")
+
+	if estimate := sketch.estimate("a"); estimate < 5 {
+		t.Fatalf("expected estimate of at least 5 for a, got %d", estimate)
+	}
+	if sketch.estimate("a") <= sketch.estimate("b") {
+		t.Fatalf("expected a to be estimated higher than b")
+	}
+}
+
+func TestCountMinSketchSaturates(t *testing.T) {
+	sketch := newCountMinSketch(1024)
+
+	for range 100 {
+		sketch.increment("a")
+	}
+
+	if estimate := sketch.estimate("a"); estimate != sketchMaxCount {
+		t.Fatalf("expected estimate to saturate at %d, got %d", sketchMaxCount, estimate)
+	}
+}
+
+func TestCountMinSketchReset(t *testing.T) {
+	sketch := newCountMinSketch(sketchMinWidth)
+
+	for range 8 {
+		sketch.increment("a")
+	}
+	before := sketch.estimate("a")
+
+	// Fill up the sample with other keys to trigger the halving
+	for range sketch.sampleSize - sketch.additions {
+		sketch.increment("filler")
+	}
+
+	if after := sketch.estimate("a"); after != before/2 {
+		t.Fatalf("expected estimate to be halved from %d, got %d", before, after)
+	}
+}
+
+func TestCountMinSketchGrowKeepsCounts(t *testing.T) {
+	sketch := newCountMinSketch(sketchMinWidth)
+
+	keys := []string{"a", "b", "c", "d"}
+	var before []uint8
+	for i, key := range keys {
+		for range i + 1 {
+			sketch.increment(key)
+		}
+		before = append(before, sketch.estimate(key))
+	}
+
+	sketch.grow()
+
+	if width := sketch.width(); width != sketchMinWidth*2 {
+		t.Fatalf("expected width %d, got %d", sketchMinWidth*2, width)
+	}
+	for i, key := range keys {
+		if estimate := sketch.estimate(key); estimate != before[i] {
+			t.Fatalf("expected estimate of %s to stay %d after growing, got %d", key, before[i], estimate)
+		}
+	}
+}
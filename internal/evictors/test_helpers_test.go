@@ -0,0 +1,75 @@
+package evictors
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// zipfTrace returns n accesses over keyCount keys following a Zipf distribution with exponent s
+func zipfTrace(seed int64, s float64, keyCount, n int) []string {
+	r := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(r, s, 1, uint64(keyCount-1))
+
+	trace := make([]string, n)
+	for i := range trace {
+		trace[i] = fmt.Sprintf("key-%d", zipf.Uint64())
+	}
+	return trace
+}
+
+// scanTrace returns n accesses to distinct keys that are never used again
+func scanTrace(prefix string, n int) []string {
+	trace := make([]string, n)
+	for i := range trace {
+		trace[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	return trace
+}
+
+// simulation replays traces against a cache that holds at most capacity keys
+// and is managed by the evictor, the same way a shard uses it.
+type simulation struct {
+	evictor  Evictor
+	capacity int
+	resident map[string]struct{}
+}
+
+func newSimulation(evictor Evictor, capacity int) *simulation {
+	return &simulation{
+		evictor:  evictor,
+		capacity: capacity,
+		resident: make(map[string]struct{}, capacity),
+	}
+}
+
+// run replays the trace and returns its hit ratio, keys cached by earlier runs stay cached
+func (s *simulation) run(trace []string) float64 {
+	hits := 0
+
+	for _, key := range trace {
+		if _, ok := s.resident[key]; ok {
+			hits++
+			s.evictor.OnGet(key)
+			continue
+		}
+
+		if len(s.resident) >= s.capacity {
+			if admitter, ok := s.evictor.(Admitter); ok && !admitter.Admit(key) {
+				continue
+			}
+			for _, evicted := range s.evictor.Evict(1) {
+				delete(s.resident, evicted)
+			}
+		}
+
+		s.evictor.OnSet(key)
+		s.resident[key] = struct{}{}
+	}
+
+	return float64(hits) / float64(len(trace))
+}
+
+// hitRatio replays the trace against an empty cache managed by the evictor
+func hitRatio(evictor Evictor, capacity int, trace []string) float64 {
+	return newSimulation(evictor, capacity).run(trace)
+}
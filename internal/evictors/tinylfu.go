@@ -0,0 +1,247 @@
+package evictors
+
+import (
+	"container/list"
+	"sync"
+)
+
+const (
+	// tinyLFUWindowPercent is the share of keys kept in the admission window
+	tinyLFUWindowPercent = 1
+
+	// tinyLFUProtectedPercent is the share of the main area reserved for keys accessed more than once
+	tinyLFUProtectedPercent = 80
+)
+
+type tinyLFUSegment uint8
+
+const (
+	windowSegment tinyLFUSegment = iota
+	probationSegment
+	protectedSegment
+)
+
+type tinyLFUEntry struct {
+	key     string
+	segment tinyLFUSegment
+
+	// candidate is set for keys that just moved from the window to probation
+	// and still have to win against the main area's victim to stay in the cache
+	candidate bool
+}
+
+// TinyLFUEvictor implements the W-TinyLFU policy used by Caffeine.
+// New keys enter a small LRU window, keys leaving the window become candidates for
+// the main area, which is a segmented LRU split into probation and protected.
+// A count-min sketch estimates how often each key was used recently, a candidate only
+// replaces the main area's victim if it was used more often, so a large scan of one-off keys
+// cannot flush the working set.
+//
+// The evictor also implements Admitter, so a new key can be rejected before it is stored
+// when it is less popular than the key that would have to make room for it.
+//
+// The evictor does not know the shard's capacity, the size of each segment is derived from the
+// number of keys it tracks.
+type TinyLFUEvictor struct {
+	mu        sync.Mutex
+	items     map[string]*list.Element
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	sketch    *countMinSketch
+
+	// admitted is the key that was last admitted, its access was already counted by Admit
+	admitted string
+}
+
+// NewTinyLFUEvictor creates a new W-TinyLFU eviction policy instance.
+func NewTinyLFUEvictor() *TinyLFUEvictor {
+	return &TinyLFUEvictor{
+		items:     make(map[string]*list.Element),
+		window:    list.New(),
+		probation: list.New(),
+		protected: list.New(),
+		sketch:    newCountMinSketch(sketchMinWidth),
+	}
+}
+
+// EntryOverhead returns the heap the evictor uses per key, see EntrySizer.
+func (t *TinyLFUEvictor) EntryOverhead() int64 {
+	return tinyLFUEntryOverhead
+}
+
+func (t *TinyLFUEvictor) OnSet(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if key == t.admitted {
+		t.admitted = ""
+	} else {
+		t.sketch.increment(key)
+	}
+
+	if el, ok := t.items[key]; ok {
+		t.touchLocked(el)
+		return
+	}
+
+	t.items[key] = t.window.PushFront(&tinyLFUEntry{key: key, segment: windowSegment})
+	t.growSketchLocked()
+	t.rebalanceLocked()
+}
+
+func (t *TinyLFUEvictor) OnGet(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	el, ok := t.items[key]
+	if !ok {
+		return
+	}
+
+	t.sketch.increment(key)
+	t.touchLocked(el)
+	t.rebalanceLocked()
+}
+
+func (t *TinyLFUEvictor) OnDelete(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if el, ok := t.items[key]; ok {
+		t.listFor(el.Value.(*tinyLFUEntry).segment).Remove(el)
+		delete(t.items, key)
+	}
+}
+
+// Evict returns count keys chosen by the W-TinyLFU policy.
+// If no keys are available to evict, it returns nil.
+func (t *TinyLFUEvictor) Evict(count int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.items) == 0 {
+		return nil
+	}
+
+	keysToEvict := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		el := t.victimLocked()
+		if el == nil {
+			break
+		}
+
+		// The candidate won the duel, it is now a regular member of the main area
+		if front := t.probation.Front(); front != nil && front != el {
+			front.Value.(*tinyLFUEntry).candidate = false
+		}
+
+		e := el.Value.(*tinyLFUEntry)
+		t.listFor(e.segment).Remove(el)
+		delete(t.items, e.key)
+
+		keysToEvict = append(keysToEvict, e.key)
+	}
+
+	return keysToEvict
+}
+
+// Admit reports whether key is used at least as often as the key that would be evicted to make room for it.
+func (t *TinyLFUEvictor) Admit(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sketch.increment(key)
+	t.admitted = key
+
+	victim := t.victimLocked()
+	if victim == nil {
+		return true
+	}
+
+	if t.sketch.estimate(key) >= t.sketch.estimate(victim.Value.(*tinyLFUEntry).key) {
+		return true
+	}
+
+	t.admitted = ""
+	return false
+}
+
+// victimLocked picks the next key to evict without removing it.
+// The victim is the tail of probation, unless the newest candidate is used less often, then the candidate goes.
+func (t *TinyLFUEvictor) victimLocked() *list.Element {
+	victim := t.probation.Back()
+	if victim == nil {
+		victim = t.protected.Back()
+	}
+	if victim == nil {
+		return t.window.Back()
+	}
+
+	candidate := t.probation.Front()
+	if candidate == nil || candidate == victim || !candidate.Value.(*tinyLFUEntry).candidate {
+		return victim
+	}
+
+	if t.sketch.estimate(candidate.Value.(*tinyLFUEntry).key) <= t.sketch.estimate(victim.Value.(*tinyLFUEntry).key) {
+		return candidate
+	}
+	return victim
+}
+
+// touchLocked records an access, probation keys are promoted to protected
+func (t *TinyLFUEvictor) touchLocked(el *list.Element) {
+	e := el.Value.(*tinyLFUEntry)
+
+	switch e.segment {
+	case windowSegment:
+		t.window.MoveToFront(el)
+	case probationSegment:
+		t.probation.Remove(el)
+		e.segment = protectedSegment
+		e.candidate = false
+		t.items[e.key] = t.protected.PushFront(e)
+	case protectedSegment:
+		t.protected.MoveToFront(el)
+	}
+}
+
+// rebalanceLocked moves keys that overflow the window to probation as candidates
+// and demotes keys that overflow protected back to probation.
+func (t *TinyLFUEvictor) rebalanceLocked() {
+	windowMax := max(1, len(t.items)*tinyLFUWindowPercent/100)
+	protectedMax := (len(t.items) - windowMax) * tinyLFUProtectedPercent / 100
+
+	for t.window.Len() > windowMax {
+		e := t.window.Remove(t.window.Back()).(*tinyLFUEntry)
+		e.segment = probationSegment
+		e.candidate = true
+		t.items[e.key] = t.probation.PushFront(e)
+	}
+
+	for t.protected.Len() > protectedMax {
+		e := t.protected.Remove(t.protected.Back()).(*tinyLFUEntry)
+		e.segment = probationSegment
+		t.items[e.key] = t.probation.PushFront(e)
+	}
+}
+
+// growSketchLocked keeps the sketch wider than the number of tracked keys,
+// a sketch that is too small overestimates every key and stops telling them apart.
+// The counts are carried over, so the keys keep their popularity while the cache fills up.
+func (t *TinyLFUEvictor) growSketchLocked() {
+	if len(t.items) > t.sketch.width() {
+		t.sketch.grow()
+	}
+}
+
+func (t *TinyLFUEvictor) listFor(segment tinyLFUSegment) *list.List {
+	switch segment {
+	case windowSegment:
+		return t.window
+	case probationSegment:
+		return t.probation
+	default:
+		return t.protected
+	}
+}
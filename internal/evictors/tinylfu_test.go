@@ -0,0 +1,121 @@
+package evictors
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTinyLFUEvictor(t *testing.T) {
+	tinyLFUEvictor := NewTinyLFUEvictor()
+
+	tinyLFUEvictor.OnSet("a")
+	tinyLFUEvictor.OnSet("b")
+	tinyLFUEvictor.OnGet("a")
+	tinyLFUEvictor.OnGet("a")
+	tinyLFUEvictor.OnSet("c")
+
+	itemsToBeEvicted := tinyLFUEvictor.Evict(1)
+
+	if itemsToBeEvicted == nil || len(itemsToBeEvicted) != 1 || itemsToBeEvicted[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", itemsToBeEvicted)
+	}
+
+	tinyLFUEvictor.OnDelete("a")
+
+	if e := tinyLFUEvictor.Evict(1); len(e) == 0 || e[0] != "c" {
+		t.Fatalf("expected c to be evicted, got %v", e)
+	}
+}
+
+func TestTinyLFUEvictorEmpty(t *testing.T) {
+	tinyLFUEvictor := NewTinyLFUEvictor()
+
+	if itemsToEvict := tinyLFUEvictor.Evict(1); len(itemsToEvict) != 0 {
+		t.Fatalf("expected no items to evict, got %d", len(itemsToEvict))
+	}
+}
+
+func TestTinyLFUEvictorAdmit(t *testing.T) {
+	tinyLFUEvictor := NewTinyLFUEvictor()
+
+	tinyLFUEvictor.OnSet("hot")
+	for range 5 {
+		tinyLFUEvictor.OnGet("hot")
+	}
+
+	// A key seen for the first time is less popular than the only key it could replace
+	if tinyLFUEvictor.Admit("one-off") {
+		t.Fatalf("expected one-off key to be rejected")
+	}
+
+	// Repeated attempts make the key popular enough to be admitted
+	admitted := false
+	for range 10 {
+		if tinyLFUEvictor.Admit("rising") {
+			admitted = true
+			break
+		}
+	}
+	if !admitted {
+		t.Fatalf("expected frequently requested key to be admitted")
+	}
+}
+
+func TestTinyLFUEvictorSketchGrowthKeepsPopularity(t *testing.T) {
+	tinyLFUEvictor := NewTinyLFUEvictor()
+
+	tinyLFUEvictor.OnSet("hot")
+	for range 5 {
+		tinyLFUEvictor.OnGet("hot")
+	}
+
+	// Enough new keys to make the sketch grow, the hot key must not lose its history
+	for i := range sketchMinWidth * 2 {
+		tinyLFUEvictor.OnSet("key-" + strconv.Itoa(i))
+	}
+
+	if estimate := tinyLFUEvictor.sketch.estimate("hot"); estimate < 6 {
+		t.Fatalf("expected the hot key to keep its frequency when the sketch grows, got %d", estimate)
+	}
+}
+
+func TestTinyLFUEvictorAdmitEmpty(t *testing.T) {
+	tinyLFUEvictor := NewTinyLFUEvictor()
+
+	if !tinyLFUEvictor.Admit("a") {
+		t.Fatalf("expected key to be admitted when there is nothing to evict")
+	}
+}
+
+func TestTinyLFUHitRatioZipf(t *testing.T) {
+	trace := zipfTrace(1, 1.1, 10_000, 200_000)
+
+	lru := hitRatio(NewLRUEvictor(), 500, trace)
+	tinyLFU := hitRatio(NewTinyLFUEvictor(), 500, trace)
+
+	t.Logf("zipf hit ratio: lru=%.3f tinylfu=%.3f", lru, tinyLFU)
+
+	if tinyLFU <= lru {
+		t.Fatalf("expected W-TinyLFU to beat LRU on a Zipf trace, lru=%.3f tinylfu=%.3f", lru, tinyLFU)
+	}
+}
+
+func TestTinyLFUHitRatioZipfWithScans(t *testing.T) {
+	zipf := zipfTrace(2, 1.1, 10_000, 200_000)
+
+	// Interleave the Zipf trace with large scans of keys that are never used again
+	trace := make([]string, 0, len(zipf)*2)
+	for i := 0; i < len(zipf); i += 20_000 {
+		trace = append(trace, zipf[i:i+20_000]...)
+		trace = append(trace, scanTrace("scan", 5_000)...)
+	}
+
+	lru := hitRatio(NewLRUEvictor(), 500, trace)
+	tinyLFU := hitRatio(NewTinyLFUEvictor(), 500, trace)
+
+	t.Logf("zipf with scans hit ratio: lru=%.3f tinylfu=%.3f", lru, tinyLFU)
+
+	if tinyLFU <= lru {
+		t.Fatalf("expected W-TinyLFU to beat LRU when scans are mixed in, lru=%.3f tinylfu=%.3f", lru, tinyLFU)
+	}
+}
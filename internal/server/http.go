@@ -4,11 +4,19 @@ import (
 	"embed"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"cache-service/internal/cache"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // TODO: Move the docs to a separate package instead of embedding them here.
@@ -24,7 +32,20 @@ var docsSubFS = func() fs.FS {
 	return sub
 }()
 
-func newHttpServer(addr string, cache *cache.Cache) *http.Server {
+// ttlHeader lets clients override the cache wide TTL for a single key
+const ttlHeader = "X-Cache-TTL"
+
+var (
+	// errPreconditionFailed is returned when the If-Match or If-None-Match header of a write does not hold
+	errPreconditionFailed = errors.New("precondition failed")
+
+	// errUnsupportedPrecondition is returned for an If-None-Match header other than *
+	errUnsupportedPrecondition = errors.New("unsupported precondition")
+)
+
+// newHttpServer builds the http server for the cache, /metrics is only served when metricsHandler is not nil.
+// Every request gets a server span from tracerProvider, a nil provider disables tracing.
+func newHttpServer(addr string, cache *cache.Cache, metricsHandler http.Handler, tracerProvider trace.TracerProvider) *http.Server {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /api/v1/cache/{key}", func(w http.ResponseWriter, r *http.Request) {
@@ -46,7 +67,44 @@ func newHttpServer(addr string, cache *cache.Cache) *http.Server {
 		handleSet(cache, w, r, key)
 	})
 
+	mux.HandleFunc("POST /api/v1/cache/{key}/incr", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := r.PathValue("key")
+
+		handleIncr(cache, w, r, key)
+	})
+
+	mux.HandleFunc("PUT /api/v1/cache/{key}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := r.PathValue("key")
+
+		handleSet(cache, w, r, key)
+	})
+
+	mux.HandleFunc("DELETE /api/v1/cache/{key}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := r.PathValue("key")
+
+		handleDelete(cache, w, r, key)
+	})
+
+	mux.HandleFunc("GET /api/v1/stats", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, cache.Stats())
+	})
+
 	mux.HandleFunc("/health", handleHealth)
+	if metricsHandler != nil {
+		mux.Handle("GET /metrics", metricsHandler)
+	}
 	mux.Handle("/docs/", http.StripPrefix("/docs/", http.FileServer(http.FS(docsSubFS))))
 	mux.Handle("/openapi.yaml", http.FileServer(http.FS(docsSubFS)))
 
@@ -61,9 +119,13 @@ func newHttpServer(addr string, cache *cache.Cache) *http.Server {
 		http.NotFound(w, r)
 	})
 
+	if tracerProvider == nil {
+		tracerProvider = noop.NewTracerProvider()
+	}
+
 	return &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: withTracing(mux, tracerProvider.Tracer(tracerName)),
 	}
 }
 
@@ -74,25 +136,181 @@ func handleSet(store *cache.Cache, w http.ResponseWriter, r *http.Request, key s
 		return
 	}
 
-	if err := store.Set(key, body); err != nil {
+	ttl, err := parseTTL(r)
+	if err != nil {
+		respondWithError(w, "invalid ttl", http.StatusBadRequest)
+		return
+	}
+
+	version, err := storeValue(store, r, key, body, ttl)
+	if err != nil {
 		switch {
-		case errors.Is(err, cache.ErrCacheFull):
+		case errors.Is(err, cache.ErrVersionMismatch), errors.Is(err, errPreconditionFailed):
+			respondWithError(w, "precondition failed", http.StatusPreconditionFailed)
+		case errors.Is(err, errUnsupportedPrecondition):
+			respondWithError(w, "only If-None-Match: * is supported", http.StatusBadRequest)
+		case errors.Is(err, cache.ErrCacheFull), errors.Is(err, cache.ErrTooManyKeys):
 			respondWithError(w, "cache is full", http.StatusInsufficientStorage)
+		case errors.Is(err, cache.ErrNotAdmitted):
+			respondWithError(w, "value rejected by admission policy", http.StatusInsufficientStorage)
 		case errors.Is(err, cache.ErrInvalidValue):
 			respondWithError(w, "invalid value", http.StatusBadRequest)
 		case errors.Is(err, cache.ErrValueTooLarge):
 			respondWithError(w, "value too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, cache.ErrInvalidTTL):
+			respondWithError(w, "invalid ttl", http.StatusBadRequest)
+		default:
+			respondWithError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleIncr adds the by query parameter, 1 by default, to the integer stored under key and responds with the result.
+// The ttl only applies if the increment creates the key.
+func handleIncr(store *cache.Cache, w http.ResponseWriter, r *http.Request, key string) {
+	delta := int64(1)
+	if raw := r.URL.Query().Get("by"); raw != "" {
+		var err error
+		if delta, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			respondWithError(w, "invalid by, expected an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ttl, err := parseTTL(r)
+	if err != nil {
+		respondWithError(w, "invalid ttl", http.StatusBadRequest)
+		return
+	}
+
+	n, err := store.IncrByContext(r.Context(), key, delta, ttl)
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrNotInteger):
+			respondWithError(w, "value is not an integer", http.StatusConflict)
+		case errors.Is(err, cache.ErrOverflow):
+			respondWithError(w, "increment would overflow", http.StatusConflict)
+		case errors.Is(err, cache.ErrCacheFull), errors.Is(err, cache.ErrTooManyKeys):
+			respondWithError(w, "cache is full", http.StatusInsufficientStorage)
+		case errors.Is(err, cache.ErrNotAdmitted):
+			respondWithError(w, "value rejected by admission policy", http.StatusInsufficientStorage)
+		case errors.Is(err, cache.ErrValueTooLarge):
+			respondWithError(w, "value too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, cache.ErrInvalidTTL):
+			respondWithError(w, "invalid ttl", http.StatusBadRequest)
 		default:
 			respondWithError(w, "internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	w.Write(strconv.AppendInt(nil, n, 10))
+}
+
+// storeValue writes the value as the conditional headers of the request ask for.
+// If-None-Match: * only creates the key, If-Match: * only replaces it
+// and If-Match with an ETag only replaces the version the client read, see the ETag of a GET.
+// Returns the version of the stored value.
+func storeValue(store *cache.Cache, r *http.Request, key string, body []byte, ttl time.Duration) (uint64, error) {
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+
+	switch {
+	case ifNoneMatch == "*":
+		return preconditionResult(store.SetIfAbsentContext(r.Context(), key, body, ttl))
+	case ifNoneMatch != "":
+		return 0, errUnsupportedPrecondition
+	case ifMatch == "*":
+		return preconditionResult(store.SetIfPresentContext(r.Context(), key, body, ttl))
+	case ifMatch != "":
+		expected, err := parseETag(ifMatch)
+		if err != nil {
+			return 0, err
+		}
+		return store.CompareAndSetContext(r.Context(), key, body, ttl, expected)
+	default:
+		return store.SetWithTTLContext(r.Context(), key, body, ttl)
+	}
+}
+
+// preconditionResult turns a conditional write that was not applied into errPreconditionFailed
+func preconditionResult(version uint64, stored bool, err error) (uint64, error) {
+	if err == nil && !stored {
+		return 0, errPreconditionFailed
+	}
+	return version, err
+}
+
+// formatETag returns the strong entity tag of a version
+func formatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseETag returns the version of a strong entity tag made by formatETag.
+// Anything else, including weak tags and lists of tags, cannot match a version.
+func parseETag(etag string) (uint64, error) {
+	unquoted, ok := strings.CutPrefix(etag, `"`)
+	if !ok {
+		return 0, errPreconditionFailed
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, errPreconditionFailed
+	}
+
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil || version == 0 {
+		return 0, errPreconditionFailed
+	}
+	return version, nil
 }
 
-func handleGet(store *cache.Cache, w http.ResponseWriter, _ *http.Request, key string) {
-	val, err := store.Get(key)
+// parseTTL reads the ttl from the X-Cache-TTL header or the ttl query parameter, the header wins if both are set.
+// The value is either a number of seconds or a Go duration such as 5m, zero means the key never expires.
+// Without either, the cache wide TTL is used.
+func parseTTL(r *http.Request) (time.Duration, error) {
+	raw := r.Header.Get(ttlHeader)
+	if raw == "" {
+		raw = r.URL.Query().Get("ttl")
+	}
+	if raw == "" {
+		return cache.DefaultExpiration, nil
+	}
+
+	ttl, err := time.ParseDuration(raw)
+	if err != nil {
+		seconds, convErr := strconv.ParseInt(raw, 10, 64)
+		if convErr != nil {
+			return 0, err
+		}
+		// Negative values are rejected before they are converted, as large ones would wrap around into a positive ttl
+		if seconds < 0 {
+			return 0, fmt.Errorf("ttl must not be negative, got %d seconds", seconds)
+		}
+		// Larger values would wrap around when converted to a time.Duration
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return 0, fmt.Errorf("ttl must be at most %d seconds, got %d", math.MaxInt64/int64(time.Second), seconds)
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	switch {
+	case ttl < 0:
+		return 0, fmt.Errorf("ttl must not be negative, got %v", ttl)
+	case ttl == 0:
+		return cache.NoExpiration, nil
+	default:
+		return ttl, nil
+	}
+}
+
+func handleGet(store *cache.Cache, w http.ResponseWriter, r *http.Request, key string) {
+	entry, err := store.GetWithMeta(r.Context(), key)
 
 	if err != nil {
 		switch {
@@ -107,8 +325,23 @@ func handleGet(store *cache.Cache, w http.ResponseWriter, _ *http.Request, key s
 	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", formatETag(entry.Version))
 	w.WriteHeader(http.StatusOK)
-	w.Write(val)
+	w.Write(entry.Value)
+}
+
+func handleDelete(store *cache.Cache, w http.ResponseWriter, _ *http.Request, key string) {
+	if err := store.Delete(key); err != nil {
+		switch {
+		case errors.Is(err, cache.ErrNotFound):
+			respondWithError(w, "key not found", http.StatusNotFound)
+		default:
+			respondWithError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -14,7 +14,7 @@ func BenchmarkHandleGet(b *testing.B) {
 	metrics := createTestMetrics(b)
 	cacheInstance, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
 	cacheInstance.Set("foo", []byte("bar"))
-	httpServer := newHttpServer(":0", cacheInstance)
+	httpServer := newHttpServer(":0", cacheInstance, nil, nil)
 	request := httptest.NewRequest(http.MethodGet, "/foo", nil)
 
 	b.ResetTimer()
@@ -27,7 +27,7 @@ func BenchmarkHandleGet(b *testing.B) {
 func BenchmarkHandleSet(b *testing.B) {
 	metrics := createTestMetrics(b)
 	cacheInstance, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
-	httpServer := newHttpServer(":0", cacheInstance)
+	httpServer := newHttpServer(":0", cacheInstance, nil, nil)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
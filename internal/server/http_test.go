@@ -3,18 +3,24 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"cache-service/internal/cache"
+	"cache-service/internal/telemetry"
+
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 func TestHandleSetGet(t *testing.T) {
 	ctx := context.Background()
 	metrics := createTestMetrics(t)
 	cacheInstance, _ := cache.NewCache(ctx, cache.WithMetrics(metrics))
-	httpServer := newHttpServer(":0", cacheInstance)
+	httpServer := newHttpServer(":0", cacheInstance, nil, nil)
 
 	// Get key api test
 	responseRecorder := httptest.NewRecorder()
@@ -39,7 +45,7 @@ func TestHandleSetGet(t *testing.T) {
 }
 
 func TestHealthEndpointOK(t *testing.T) {
-	srv := newHttpServer(":0", nil)
+	srv := newHttpServer(":0", nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rr := httptest.NewRecorder()
@@ -56,7 +62,7 @@ func TestHealthEndpointOK(t *testing.T) {
 }
 
 func TestHealthEndpointMethodNotAllowed(t *testing.T) {
-	srv := newHttpServer(":0", nil)
+	srv := newHttpServer(":0", nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/health", nil)
 	rr := httptest.NewRecorder()
@@ -70,7 +76,7 @@ func TestHealthEndpointMethodNotAllowed(t *testing.T) {
 func TestHandleSetMissingValue(t *testing.T) {
 	metrics := createTestMetrics(t)
 	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
-	srv := newHttpServer(":0", c)
+	srv := newHttpServer(":0", c, nil, nil)
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/a", nil)
@@ -85,7 +91,7 @@ func TestHandleSetMissingValue(t *testing.T) {
 func TestHandleGetNotFound(t *testing.T) {
 	metrics := createTestMetrics(t)
 	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
-	srv := newHttpServer(":0", c)
+	srv := newHttpServer(":0", c, nil, nil)
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/cache/missing", nil)
@@ -97,10 +103,300 @@ func TestHandleGetNotFound(t *testing.T) {
 	}
 }
 
+func TestHandleSetWithTTL(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/header", bytes.NewBufferString("v"))
+	req.Header.Set(ttlHeader, "1")
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/cache/query?ttl=10ms", bytes.NewBufferString("v"))
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := c.Get("header"); err != nil {
+		t.Fatalf("expected key with 1s ttl to be present, got %v", err)
+	}
+	if _, err := c.Get("query"); err != cache.ErrExpired {
+		t.Fatalf("expected key with 10ms ttl to expire, got %v", err)
+	}
+}
+
+func TestHandleSetIfMatch(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+	c.Set("key", []byte("v1"))
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/cache/key", nil))
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected GET to return an ETag")
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/key", bytes.NewBufferString("v2"))
+	req.Header.Set("If-Match", etag)
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the write with the current ETag to succeed, got %d", rr.Code)
+	}
+	if newETag := rr.Header().Get("ETag"); newETag == "" || newETag == etag {
+		t.Fatalf("expected the write to return the new ETag, got %q", newETag)
+	}
+
+	// The old ETag no longer matches, and neither do tags this server never hands out
+	for _, ifMatch := range []string{etag, "W/" + etag, `"abc"`} {
+		rr = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/cache/key", bytes.NewBufferString("v3"))
+		req.Header.Set("If-Match", ifMatch)
+		srv.Handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusPreconditionFailed {
+			t.Fatalf("expected 412 for If-Match %s, got %d", ifMatch, rr.Code)
+		}
+	}
+
+	if value, _ := c.Get("key"); string(value) != "v2" {
+		t.Fatalf("expected the failed writes to leave v2, got %q", value)
+	}
+}
+
+func TestHandleSetReturnsETag(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	for _, header := range []string{"", "If-None-Match", "If-Match"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/cache/key?ttl=60", bytes.NewBufferString("v"))
+		if header != "" {
+			req.Header.Set(header, "*")
+			c.Delete("key")
+			if header == "If-Match" {
+				c.Set("key", []byte("old"))
+			}
+		}
+		srv.Handler.ServeHTTP(rr, req)
+
+		entry, _ := c.GetWithMeta(context.Background(), "key")
+		if rr.Code != http.StatusOK || rr.Header().Get("ETag") != formatETag(entry.Version) {
+			t.Fatalf("expected the write with %q to return the ETag of the stored value, got %d %q", header, rr.Code, rr.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestHandleSetIfNoneMatchAndIfMatchAny(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	put := func(ifMatchHeader, ifMatch, value string) int {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/cache/lock", bytes.NewBufferString(value))
+		req.Header.Set(ifMatchHeader, ifMatch)
+		srv.Handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := put("If-Match", "*", "v0"); code != http.StatusPreconditionFailed {
+		t.Fatalf("expected If-Match: * to fail for a missing key, got %d", code)
+	}
+	if code := put("If-None-Match", "*", "v1"); code != http.StatusOK {
+		t.Fatalf("expected If-None-Match: * to create the key, got %d", code)
+	}
+	if code := put("If-None-Match", "*", "v2"); code != http.StatusPreconditionFailed {
+		t.Fatalf("expected If-None-Match: * to fail for an existing key, got %d", code)
+	}
+	if code := put("If-Match", "*", "v3"); code != http.StatusOK {
+		t.Fatalf("expected If-Match: * to replace the existing key, got %d", code)
+	}
+	if code := put("If-None-Match", `"1"`, "v4"); code != http.StatusBadRequest {
+		t.Fatalf("expected If-None-Match with an ETag to be rejected, got %d", code)
+	}
+
+	if value, _ := c.Get("lock"); string(value) != "v3" {
+		t.Fatalf("expected v3, got %q", value)
+	}
+}
+
+func TestParseETag(t *testing.T) {
+	if version, err := parseETag(formatETag(42)); err != nil || version != 42 {
+		t.Fatalf("expected the ETag to parse back to 42, got %d, %v", version, err)
+	}
+	for _, etag := range []string{"42", `"42`, `W/"42"`, `"0"`, `"-1"`, `"1", "2"`} {
+		if _, err := parseETag(etag); err == nil {
+			t.Fatalf("expected %s not to parse", etag)
+		}
+	}
+}
+
+func TestHandleSetInvalidTTL(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	// Seconds that do not fit in a time.Duration must not wrap around into some other ttl
+	for _, ttl := range []string{"abc", "-5", "-1m", "9223372037", "-9223372037", "18446744074"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/foo", bytes.NewBufferString("v"))
+		req.Header.Set(ttlHeader, ttl)
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for ttl %q, got %d", ttl, rr.Code)
+		}
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    cache.DefaultExpiration,
+		"0":   cache.NoExpiration,
+		"300": 300 * time.Second,
+		"5m":  5 * time.Minute,
+	}
+
+	for raw, expected := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/foo", nil)
+		req.Header.Set(ttlHeader, raw)
+
+		ttl, err := parseTTL(req)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", raw, err)
+		}
+		if ttl != expected {
+			t.Fatalf("expected %v for %q, got %v", expected, raw, ttl)
+		}
+	}
+}
+
+func TestHandleDelete(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	c.Set("foo", []byte("bar"))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/cache/foo", nil)
+	srv.Handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/cache/foo", nil)
+	srv.Handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/cache/foo", nil)
+	srv.Handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing key, got %d", rr.Code)
+	}
+}
+
+func TestHandleIncr(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	incr := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, nil))
+		return rr
+	}
+
+	steps := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/v1/cache/hits/incr", "1"},
+		{"/api/v1/cache/hits/incr?by=10", "11"},
+		{"/api/v1/cache/hits/incr?by=-20", "-9"},
+	}
+	for _, step := range steps {
+		rr := incr(step.path)
+		if rr.Code != http.StatusOK || rr.Body.String() != step.expected {
+			t.Fatalf("expected 200 and %s for %s, got %d and %q", step.expected, step.path, rr.Code, rr.Body.String())
+		}
+	}
+
+	if value, _ := c.Get("hits"); string(value) != "-9" {
+		t.Fatalf("expected the counter to be stored as -9, got %q", value)
+	}
+
+	if rr := incr("/api/v1/cache/hits/incr?by=abc"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid by, got %d", rr.Code)
+	}
+
+	c.Set("name", []byte("bar"))
+	if rr := incr("/api/v1/cache/name/incr"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a value that is not an integer, got %d", rr.Code)
+	}
+
+	c.Set("max", []byte("9223372036854775807"))
+	if rr := incr("/api/v1/cache/max/incr"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on overflow, got %d", rr.Code)
+	}
+}
+
+func TestHandleSetTooManyKeys(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithShardCount(1), cache.WithMaxKeys(1), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+	c.Set("other", []byte("v"))
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		for _, ifNoneMatch := range []string{"", "*"} {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(method, "/api/v1/cache/key", bytes.NewBufferString("v"))
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			srv.Handler.ServeHTTP(rr, req)
+			if rr.Code != http.StatusInsufficientStorage {
+				t.Fatalf("expected 507 for %s with If-None-Match %q when no key is left, got %d", method, ifNoneMatch, rr.Code)
+			}
+		}
+	}
+}
+
+func TestHandleIncrTooManyKeys(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithShardCount(1), cache.WithMaxKeys(1), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+	c.Set("other", []byte("v"))
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/cache/hits/incr", nil))
+	if rr.Code != http.StatusInsufficientStorage {
+		t.Fatalf("expected 507 when no key is left for the counter, got %d", rr.Code)
+	}
+}
+
 func TestHandleSetCacheFull(t *testing.T) {
 	metrics := createTestMetrics(t)
 	c, _ := cache.NewCache(context.Background(), cache.WithShardCount(1), cache.WithMaxSize(2), cache.WithMetrics(metrics))
-	srv := newHttpServer(":0", c)
+	srv := newHttpServer(":0", c, nil, nil)
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/foo", bytes.NewBufferString("aaa"))
@@ -113,7 +409,7 @@ func TestHandleSetCacheFull(t *testing.T) {
 }
 
 func TestDocsEndpoints(t *testing.T) {
-	srv := newHttpServer(":0", nil)
+	srv := newHttpServer(":0", nil, nil, nil)
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/docs/swagger.html", nil)
@@ -129,3 +425,80 @@ func TestDocsEndpoints(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	provider, metricsHandler, shutdown, err := telemetry.NewMeterProvider(ctx, telemetry.ExporterPrometheus, resource.Empty())
+	if err != nil {
+		t.Fatalf("failed to create meter provider: %v", err)
+	}
+	defer shutdown()
+
+	metrics, err := telemetry.NewCacheMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("failed to create metrics: %v", err)
+	}
+	c, _ := cache.NewCache(ctx, cache.WithShardCount(1), cache.WithMaxSize(4), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, metricsHandler, nil)
+
+	// Generate a hit, a miss, an eviction and an error so every counter has a data point
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/v1/cache/a", bytes.NewBufferString("1111")),
+		httptest.NewRequest(http.MethodGet, "/api/v1/cache/a", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/cache/missing", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/cache/b", bytes.NewBufferString("2")),
+		httptest.NewRequest(http.MethodPost, "/api/v1/cache/c", bytes.NewBufferString("too large")),
+	} {
+		srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	body := rr.Body.String()
+	for _, name := range []string{"cache_hits", "cache_misses", "cache_sets", "cache_evictions", "cache_error_count", "cache_operation_latency"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("expected %s in the scrape", name)
+		}
+	}
+}
+
+func TestMetricsEndpointDisabled(t *testing.T) {
+	srv := newHttpServer(":0", nil, nil, nil)
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rr.Body.String(), "# TYPE") {
+		t.Fatalf("expected /metrics not to be served without a metrics handler")
+	}
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	metrics := createTestMetrics(t)
+	c, _ := cache.NewCache(context.Background(), cache.WithShardCount(2), cache.WithMetrics(metrics))
+	srv := newHttpServer(":0", c, nil, nil)
+
+	c.Set("a", []byte("123"))
+	c.Get("a")
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var stats cache.Stats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if stats.Keys != 1 || stats.Bytes != 3 || stats.Hits != 1 || len(stats.Shards) != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	// One of the two shards holds the only key, twice the mean
+	if stats.Skew.Keys != 2 {
+		t.Fatalf("expected a key skew of 2, got %v", stats.Skew.Keys)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CacheServer provides an http server which can be used to interact with the cache
@@ -14,8 +16,30 @@ type CacheServer struct {
 	Http *http.Server
 }
 
+// ServerOption configures optional endpoints of the CacheServer
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	metricsHandler http.Handler
+	tracerProvider trace.TracerProvider
+}
+
+// WithMetricsHandler serves the handler on GET /metrics, e.g. the handler of the prometheus exporter
+func WithMetricsHandler(handler http.Handler) ServerOption {
+	return func(o *serverOptions) {
+		o.metricsHandler = handler
+	}
+}
+
+// WithTracerProvider creates a server span for every http request with the given provider
+func WithTracerProvider(provider trace.TracerProvider) ServerOption {
+	return func(o *serverOptions) {
+		o.tracerProvider = provider
+	}
+}
+
 // NewCacheServer constructs a server instance
-func NewCacheServer(port int, cache *cache.Cache) (*CacheServer, error) {
+func NewCacheServer(port int, cache *cache.Cache, opts ...ServerOption) (*CacheServer, error) {
 	if cache == nil {
 		return nil, fmt.Errorf("cache cannot be nil")
 	}
@@ -23,9 +47,14 @@ func NewCacheServer(port int, cache *cache.Cache) (*CacheServer, error) {
 		return nil, fmt.Errorf("port cannot be less than or equal to 0")
 	}
 
+	options := serverOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	httpAddr := fmt.Sprintf(":%d", port)
 
-	httpServer := newHttpServer(httpAddr, cache)
+	httpServer := newHttpServer(httpAddr, cache, options.metricsHandler, options.tracerProvider)
 	return &CacheServer{Http: httpServer}, nil
 }
 
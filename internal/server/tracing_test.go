@@ -0,0 +1,62 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cache-service/internal/cache"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestServerSpanContinuesIncomingTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	c, _ := cache.NewCache(context.Background(), cache.WithTracerProvider(provider), cache.WithMetrics(createTestMetrics(t)))
+	srv := newHttpServer(":0", c, nil, provider)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cache/foo", bytes.NewBufferString("bar"))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a cache span and a server span, got %d", len(spans))
+	}
+
+	cacheSpan, serverSpan := spans[0], spans[1]
+	if serverSpan.Name() != "POST /api/v1/cache/{key}" {
+		t.Fatalf("expected the span to be named after the route, got %q", serverSpan.Name())
+	}
+	if serverSpan.SpanContext().TraceID().String() != traceID || serverSpan.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("expected the server span to continue the incoming trace")
+	}
+	if cacheSpan.Name() != "cache.Set" || cacheSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Fatalf("expected the cache span to be a child of the server span")
+	}
+}
+
+func TestServerSpanRecordsStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	c, _ := cache.NewCache(context.Background(), cache.WithMetrics(createTestMetrics(t)))
+	srv := newHttpServer(":0", c, nil, provider)
+
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/cache/missing", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one server span, got %d", len(spans))
+	}
+	for _, kv := range spans[0].Attributes() {
+		if kv.Key == "http.response.status_code" && kv.Value.AsInt64() == http.StatusNotFound {
+			return
+		}
+	}
+	t.Fatalf("expected the 404 status code on the span, got %v", spans[0].Attributes())
+}
@@ -2,42 +2,175 @@ package telemetry
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
 )
 
-// NewMeterProvider creates a basic meter provider that exports metrics to stdout
-// Can be modified or a new one can plugged in to export metrics to something else
-func NewMeterProvider(ctx context.Context) (*sdkmetric.MeterProvider, func(), error) {
-	exporter, err := stdoutmetric.New(stdoutmetric.WithPrettyPrint())
-	if err != nil {
-		log.Fatalf("failed to create stdout exporter: %v", err)
-		return nil, nil, err
+// Metrics exporters that can be passed to NewMeterProvider
+const (
+	ExporterStdout     = "stdout"
+	ExporterPrometheus = "prometheus"
+	ExporterOTLP       = "otlp"
+	ExporterNone       = "none"
+)
+
+// ValidateMetricsExporter returns an error if exporter is not one of the supported metrics exporters
+func ValidateMetricsExporter(exporter string) error {
+	switch exporter {
+	case ExporterStdout, ExporterPrometheus, ExporterOTLP, ExporterNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown metrics exporter %q, expected one of %s, %s, %s or %s",
+			exporter, ExporterStdout, ExporterPrometheus, ExporterOTLP, ExporterNone)
+	}
+}
+
+// NewMeterProvider creates a meter provider that exports metrics with the given exporter.
+// For the prometheus exporter the returned handler serves the metrics in the Prometheus text format
+// and should be mounted on /metrics, for every other exporter it is nil.
+// The otlp exporter pushes to the collector configured through the OTEL_EXPORTER_OTLP_* environment variables.
+func NewMeterProvider(ctx context.Context, exporter string, res *sdkresource.Resource) (*sdkmetric.MeterProvider, http.Handler, func(), error) {
+	opts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	var handler http.Handler
+
+	switch exporter {
+	case ExporterStdout:
+		stdoutExporter, err := stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(stdoutExporter)))
+
+	case ExporterPrometheus:
+		// A registry of our own keeps the default registry's Go runtime collectors out of the output
+		registry := prometheus.NewRegistry()
+		prometheusExporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+		}
+		opts = append(opts, sdkmetric.WithReader(prometheusExporter))
+		handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+	case ExporterOTLP:
+		otlpExporter, err := newOTLPMetricExporter(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(otlpExporter)))
+
+	case ExporterNone:
+		// A provider without readers still hands out working instruments, their measurements are dropped
+
+	default:
+		return nil, nil, nil, ValidateMetricsExporter(exporter)
 	}
 
-	reader := sdkmetric.NewPeriodicReader(exporter)
-	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	provider := sdkmetric.NewMeterProvider(opts...)
 	shutdown := func() {
-		err = provider.Shutdown(ctx)
+		// The context is usually cancelled by the time we shut down, pending metrics still have to be flushed
+		err := provider.Shutdown(context.WithoutCancel(ctx))
 		if err != nil {
-			log.Fatalf("failed to shutdown meter provider: %v", err)
+			// Losing the last batch of metrics is no reason to fail the shutdown
+			slog.Error("failed to shutdown meter provider", "err", err)
 		}
 	}
 
-	return provider, shutdown, nil
+	return provider, handler, shutdown, nil
+}
+
+// Attribute keys used on the cache metrics
+const (
+	// OperationKey is the cache operation a latency was recorded for, e.g. get or set
+	OperationKey = attribute.Key("operation")
+
+	// ResultKey is the outcome of the operation, e.g. hit, miss, ok, condition_failed or error
+	ResultKey = attribute.Key("result")
+
+	// ReasonKey is why a key was evicted, e.g. capacity or expired
+	ReasonKey = attribute.Key("reason")
+
+	// ErrorKey is the kind of error that was counted, e.g. cache_full
+	ErrorKey = attribute.Key("error")
+
+	// ShardKey is the shard a per shard measurement belongs to
+	ShardKey = attribute.Key("shard")
+)
+
+// latencyBuckets are the histogram boundaries for operation latency, from 1µs to 1s
+var latencyBuckets = []float64{
+	0.000001, 0.0000025, 0.000005, 0.00001, 0.000025, 0.00005, 0.0001,
+	0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.1, 1,
 }
 
 type CacheMetrics struct {
-	Hits       metric.Int64Counter
-	Misses     metric.Int64Counter
-	Sets       metric.Int64Counter
-	Evictions  metric.Int64Counter
-	ItemCount  metric.Int64Counter
-	Latency    metric.Float64Histogram
-	ErrorCount metric.Float64Counter
+	Hits        metric.Int64Counter
+	Misses      metric.Int64Counter
+	Sets        metric.Int64Counter
+	Evictions   metric.Int64Counter
+	Expirations metric.Int64Counter
+	Latency     metric.Float64Histogram // seconds, by operation and result
+	ErrorCount  metric.Int64Counter     // by error
+
+	// Gauges read from the cache on every collection, see ObserveShards
+	ItemCount        metric.Int64ObservableGauge
+	SizeBytes        metric.Int64ObservableGauge
+	MaxSizeBytes     metric.Int64ObservableGauge
+	MaxKeys          metric.Int64ObservableGauge
+	ShardUtilization metric.Float64ObservableGauge // by shard
+
+	meter metric.Meter
+}
+
+// CacheStats is the state of the cache at the time metrics are collected
+type CacheStats struct {
+	MaxBytes int64 // capacity of the whole cache, which its shards share
+	MaxKeys  int
+	Shards   []ShardStats
+}
+
+// ShardStats is the state of a single shard at the time metrics are collected
+type ShardStats struct {
+	ID        string
+	Items     int
+	SizeBytes int64
+}
+
+// ObserveShards reports the cache returned by stats through the gauges every time metrics are collected.
+// The item count and size are the sums over the shards, the capacity is the one the shards share.
+// A shard's utilization is its share of that capacity, as a shard may hold more than an equal share
+// while the others have room to spare.
+// Unregister the returned registration once the shards are gone.
+func (m *CacheMetrics) ObserveShards(stats func() CacheStats) (metric.Registration, error) {
+	return m.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		var items, sizeBytes int64
+
+		cache := stats()
+		for _, shard := range cache.Shards {
+			items += int64(shard.Items)
+			sizeBytes += shard.SizeBytes
+
+			if cache.MaxBytes > 0 {
+				utilization := float64(shard.SizeBytes) / float64(cache.MaxBytes)
+				o.ObserveFloat64(m.ShardUtilization, utilization, metric.WithAttributes(ShardKey.String(shard.ID)))
+			}
+		}
+
+		o.ObserveInt64(m.ItemCount, items)
+		o.ObserveInt64(m.SizeBytes, sizeBytes)
+		o.ObserveInt64(m.MaxSizeBytes, cache.MaxBytes)
+		o.ObserveInt64(m.MaxKeys, int64(cache.MaxKeys))
+		return nil
+	}, m.ItemCount, m.SizeBytes, m.MaxSizeBytes, m.MaxKeys, m.ShardUtilization)
 }
 
 // NewCacheMetrics creates metric counters used by the cache.
@@ -57,33 +190,78 @@ func NewCacheMetrics(m metric.Meter) (*CacheMetrics, error) {
 		return nil, err
 	}
 
-	evictions, err := m.Int64Counter("cache_evictions")
+	evictions, err := m.Int64Counter("cache_evictions",
+		metric.WithDescription("Keys removed by the cache to make space or because they expired"))
+	if err != nil {
+		return nil, err
+	}
+
+	expirations, err := m.Int64Counter("cache_expirations")
+	if err != nil {
+		return nil, err
+	}
+
+	itemCount, err := m.Int64ObservableGauge("cache_item_count",
+		metric.WithDescription("Number of items stored in the cache"))
 	if err != nil {
 		return nil, err
 	}
 
-	itemCount, err := m.Int64Counter("cache_item_count")
+	sizeBytes, err := m.Int64ObservableGauge("cache_size_bytes",
+		metric.WithDescription("Bytes used by the values stored in the cache"),
+		metric.WithUnit("By"))
 	if err != nil {
 		return nil, err
 	}
 
-	getLatency, err := m.Float64Histogram("cache_get_latency")
+	maxSizeBytes, err := m.Int64ObservableGauge("cache_max_size_bytes",
+		metric.WithDescription("Configured capacity of the cache in bytes"),
+		metric.WithUnit("By"))
 	if err != nil {
 		return nil, err
 	}
 
-	errorCount, err := m.Float64Counter("cache_error_count")
+	maxKeys, err := m.Int64ObservableGauge("cache_max_keys",
+		metric.WithDescription("Configured capacity of the cache in keys"))
+	if err != nil {
+		return nil, err
+	}
+
+	shardUtilization, err := m.Float64ObservableGauge("cache_shard_utilization",
+		metric.WithDescription("Fraction of the cache's byte capacity held by a shard"))
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := m.Float64Histogram("cache_operation_latency",
+		metric.WithDescription("Duration of cache operations"),
+		metric.WithUnit("s"),
+		// Cache operations take microseconds, the default boundaries are meant for milliseconds
+		metric.WithExplicitBucketBoundaries(latencyBuckets...))
+	if err != nil {
+		return nil, err
+	}
+
+	errorCount, err := m.Int64Counter("cache_error_count")
 	if err != nil {
 		return nil, err
 	}
 
 	return &CacheMetrics{
-		Hits:       hits,
-		Misses:     misses,
-		Sets:       sets,
-		Evictions:  evictions,
-		ItemCount:  itemCount,
-		Latency:    getLatency,
-		ErrorCount: errorCount,
+		Hits:        hits,
+		Misses:      misses,
+		Sets:        sets,
+		Evictions:   evictions,
+		Expirations: expirations,
+		Latency:     latency,
+		ErrorCount:  errorCount,
+
+		ItemCount:        itemCount,
+		SizeBytes:        sizeBytes,
+		MaxSizeBytes:     maxSizeBytes,
+		MaxKeys:          maxKeys,
+		ShardUtilization: shardUtilization,
+
+		meter: m,
 	}, nil
 }
@@ -2,22 +2,64 @@ package telemetry
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 func TestNewMeterProvider(t *testing.T) {
-	provider, shutdown, err := NewMeterProvider(context.Background())
+	// otlp is covered against a fake collector in otlp_test.go
+	for _, exporter := range []string{ExporterStdout, ExporterPrometheus, ExporterNone} {
+		provider, handler, shutdown, err := NewMeterProvider(context.Background(), exporter, resource.Empty())
 
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", exporter, err)
+		}
+
+		if provider == nil || shutdown == nil {
+			t.Fatalf("%s: provider or shutdown was nil", exporter)
+		}
+		if (handler != nil) != (exporter == ExporterPrometheus) {
+			t.Fatalf("%s: expected a metrics handler only for prometheus", exporter)
+		}
+		shutdown()
+	}
+}
+
+func TestNewMeterProviderUnknownExporter(t *testing.T) {
+	if _, _, _, err := NewMeterProvider(context.Background(), "statsd", resource.Empty()); err == nil {
+		t.Fatalf("expected error for unknown exporter")
+	}
+}
+
+func TestPrometheusHandlerServesCacheMetrics(t *testing.T) {
+	provider, handler, shutdown, err := NewMeterProvider(context.Background(), ExporterPrometheus, resource.Empty())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	defer shutdown()
+
+	metrics, err := NewCacheMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metrics.Hits.Add(context.Background(), 3)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
 
-	if provider == nil || shutdown == nil {
-		t.Fatalf("provider or shutdown was nil")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "cache_hits_total") {
+		t.Fatalf("expected cache_hits in the scrape, got %s", rec.Body.String())
 	}
-	shutdown()
 }
 
 func TestNewCacheMetrics(t *testing.T) {
@@ -27,7 +69,73 @@ func TestNewCacheMetrics(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if metrics == nil || metrics.Hits == nil || metrics.Misses == nil || metrics.Sets == nil || metrics.Evictions == nil || metrics.ItemCount == nil || metrics.Latency == nil || metrics.ErrorCount == nil {
+	if metrics == nil || metrics.Hits == nil || metrics.Misses == nil || metrics.Sets == nil || metrics.Evictions == nil || metrics.Expirations == nil || metrics.Latency == nil || metrics.ErrorCount == nil ||
+		metrics.ItemCount == nil || metrics.SizeBytes == nil || metrics.MaxSizeBytes == nil || metrics.MaxKeys == nil || metrics.ShardUtilization == nil {
 		t.Fatalf("metrics not properly initialized")
 	}
 }
+
+func TestObserveShards(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	metrics, err := NewCacheMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	registration, err := metrics.ObserveShards(func() CacheStats {
+		return CacheStats{
+			MaxBytes: 200,
+			MaxKeys:  10,
+			Shards: []ShardStats{
+				{ID: "s1", Items: 2, SizeBytes: 100},
+				{ID: "s2", Items: 1, SizeBytes: 50},
+			},
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect: %v", err)
+	}
+
+	int64Gauges := map[string]int64{}
+	utilization := map[string]float64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch data := m.Data.(type) {
+		case metricdata.Gauge[int64]:
+			int64Gauges[m.Name] = data.DataPoints[0].Value
+		case metricdata.Gauge[float64]:
+			for _, point := range data.DataPoints {
+				shard, _ := point.Attributes.Value(ShardKey)
+				utilization[shard.AsString()] = point.Value
+			}
+		}
+	}
+
+	// The capacity is the cache's, not the sum of per shard shares
+	expected := map[string]int64{"cache_item_count": 3, "cache_size_bytes": 150, "cache_max_size_bytes": 200, "cache_max_keys": 10}
+	for name, value := range expected {
+		if int64Gauges[name] != value {
+			t.Errorf("expected %s to be %d, got %d", name, value, int64Gauges[name])
+		}
+	}
+	if utilization["s1"] != 0.5 || utilization["s2"] != 0.25 {
+		t.Errorf("unexpected shard utilization %v", utilization)
+	}
+
+	// Nothing is reported for the shards once the registration is gone
+	registration.Unregister()
+	rm = metricdata.ResourceMetrics{}
+	reader.Collect(context.Background(), &rm)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if gauge, ok := m.Data.(metricdata.Gauge[int64]); ok && len(gauge.DataPoints) > 0 {
+				t.Fatalf("expected no data points after unregistering, got %s", m.Name)
+			}
+		}
+	}
+}
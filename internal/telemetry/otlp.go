@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLP transport protocols, as named by OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// otlpProtocol returns the transport protocol for a signal ("METRICS" or "TRACES").
+// The signal specific OTEL_EXPORTER_OTLP_<SIGNAL>_PROTOCOL wins over OTEL_EXPORTER_OTLP_PROTOCOL,
+// without either the specification's default of http/protobuf is used.
+func otlpProtocol(signal string) (string, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+
+	switch protocol {
+	case "", ProtocolHTTPProtobuf:
+		return ProtocolHTTPProtobuf, nil
+	case ProtocolGRPC:
+		return ProtocolGRPC, nil
+	default:
+		return "", fmt.Errorf("unsupported otlp protocol %q, expected %s or %s", protocol, ProtocolGRPC, ProtocolHTTPProtobuf)
+	}
+}
+
+// newOTLPMetricExporter creates an OTLP metric exporter over the configured protocol.
+// Endpoint, headers, TLS and timeouts are read from the OTEL_EXPORTER_OTLP_* environment variables by the exporter itself.
+func newOTLPMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	protocol, err := otlpProtocol("METRICS")
+	if err != nil {
+		return nil, err
+	}
+
+	if protocol == ProtocolGRPC {
+		return otlpmetricgrpc.New(ctx)
+	}
+	return otlpmetrichttp.New(ctx)
+}
+
+// newOTLPTraceExporter creates an OTLP span exporter over the configured protocol,
+// configured through the OTEL_EXPORTER_OTLP_* environment variables like the metric exporter.
+func newOTLPTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol, err := otlpProtocol("TRACES")
+	if err != nil {
+		return nil, err
+	}
+
+	if protocol == ProtocolGRPC {
+		return otlptracegrpc.New(ctx)
+	}
+	return otlptracehttp.New(ctx)
+}
@@ -0,0 +1,75 @@
+package telemetry
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestOTLPProtocol(t *testing.T) {
+	if protocol, err := otlpProtocol("METRICS"); err != nil || protocol != ProtocolHTTPProtobuf {
+		t.Fatalf("expected http/protobuf by default, got %q %v", protocol, err)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", ProtocolGRPC)
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", ProtocolHTTPProtobuf)
+
+	if protocol, _ := otlpProtocol("METRICS"); protocol != ProtocolGRPC {
+		t.Fatalf("expected grpc from OTEL_EXPORTER_OTLP_PROTOCOL, got %q", protocol)
+	}
+	if protocol, _ := otlpProtocol("TRACES"); protocol != ProtocolHTTPProtobuf {
+		t.Fatalf("expected the signal specific protocol to win, got %q", protocol)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := otlpProtocol("METRICS"); err == nil {
+		t.Fatalf("expected error for unsupported protocol")
+	}
+}
+
+func TestOTLPExportToCollector(t *testing.T) {
+	for _, protocol := range []string{ProtocolGRPC, ProtocolHTTPProtobuf} {
+		t.Run(protocol, func(t *testing.T) {
+			ctx := context.Background()
+			collector := newFakeCollector(t, protocol)
+
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.endpoint)
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+			t.Setenv("OTEL_SERVICE_NAME", "cache-test")
+
+			res, err := NewResource(ctx)
+			if err != nil {
+				t.Fatalf("failed to create resource: %v", err)
+			}
+
+			meterProvider, _, shutdownMeterProvider, err := NewMeterProvider(ctx, ExporterOTLP, res)
+			if err != nil {
+				t.Fatalf("failed to create meter provider: %v", err)
+			}
+			tracerProvider, shutdownTracerProvider, err := NewTracerProvider(ctx, ExporterOTLP, res)
+			if err != nil {
+				t.Fatalf("failed to create tracer provider: %v", err)
+			}
+
+			metrics, _ := NewCacheMetrics(meterProvider.Meter("test"))
+			metrics.Hits.Add(ctx, 1)
+			_, span := tracerProvider.Tracer("test").Start(ctx, "cache.get")
+			span.End()
+
+			// Shutting down flushes whatever has not been exported yet
+			shutdownMeterProvider()
+			shutdownTracerProvider()
+
+			metricNames, spanNames, serviceNames := collector.received()
+			if !slices.Contains(metricNames, "cache_hits") {
+				t.Errorf("expected cache_hits to be exported, got %v", metricNames)
+			}
+			if !slices.Contains(spanNames, "cache.get") {
+				t.Errorf("expected the span to be exported, got %v", spanNames)
+			}
+			if len(serviceNames) == 0 || slices.ContainsFunc(serviceNames, func(name string) bool { return name != "cache-test" }) {
+				t.Errorf("expected every export to carry the service name from OTEL_SERVICE_NAME, got %v", serviceNames)
+			}
+		})
+	}
+}
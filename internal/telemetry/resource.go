@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// DefaultServiceName is reported as service.name unless OTEL_SERVICE_NAME is set
+const DefaultServiceName = "cache-service"
+
+// NewResource describes this service to the telemetry backends.
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the service name and add attributes,
+// so every instance can be tagged with its environment, region and so on.
+func NewResource(ctx context.Context) (*resource.Resource, error) {
+	return resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(DefaultServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		// Detected last, so the environment wins over the defaults above
+		resource.WithFromEnv(),
+	)
+}
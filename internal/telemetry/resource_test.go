@@ -0,0 +1,32 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestNewResource(t *testing.T) {
+	res, err := NewResource(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name, _ := res.Set().Value("service.name"); name.AsString() != DefaultServiceName {
+		t.Fatalf("expected default service name, got %q", name.AsString())
+	}
+
+	t.Setenv("OTEL_SERVICE_NAME", "cache-eu")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=staging")
+
+	res, err = NewResource(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name, _ := res.Set().Value("service.name"); name.AsString() != "cache-eu" {
+		t.Fatalf("expected OTEL_SERVICE_NAME to win, got %q", name.AsString())
+	}
+	if env, _ := res.Set().Value(attribute.Key("deployment.environment")); env.AsString() != "staging" {
+		t.Fatalf("expected resource attributes from OTEL_RESOURCE_ATTRIBUTES, got %q", env.AsString())
+	}
+}
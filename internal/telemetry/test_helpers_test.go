@@ -0,0 +1,149 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeCollector is an in-process OTLP collector that remembers what it received,
+// it listens over gRPC or HTTP depending on the protocol it was started with
+type fakeCollector struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	endpoint string
+
+	mu           sync.Mutex
+	metricNames  []string
+	spanNames    []string
+	serviceNames []string
+}
+
+func newFakeCollector(tb testing.TB, protocol string) *fakeCollector {
+	tb.Helper()
+	collector := &fakeCollector{}
+
+	if protocol == ProtocolGRPC {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			tb.Fatalf("failed to listen: %v", err)
+		}
+		server := grpc.NewServer()
+		colmetricspb.RegisterMetricsServiceServer(server, collector)
+		coltracepb.RegisterTraceServiceServer(server, traceService{collector: collector})
+		go server.Serve(listener)
+		tb.Cleanup(server.Stop)
+
+		collector.endpoint = "http://" + listener.Addr().String()
+		return collector
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/metrics", func(w http.ResponseWriter, r *http.Request) {
+		request := &colmetricspb.ExportMetricsServiceRequest{}
+		if !readProto(w, r, request) {
+			return
+		}
+		collector.Export(r.Context(), request)
+		writeProto(w, &colmetricspb.ExportMetricsServiceResponse{})
+	})
+	mux.HandleFunc("POST /v1/traces", func(w http.ResponseWriter, r *http.Request) {
+		request := &coltracepb.ExportTraceServiceRequest{}
+		if !readProto(w, r, request) {
+			return
+		}
+		collector.exportTraces(request)
+		writeProto(w, &coltracepb.ExportTraceServiceResponse{})
+	})
+	server := httptest.NewServer(mux)
+	tb.Cleanup(server.Close)
+
+	collector.endpoint = server.URL
+	return collector
+}
+
+func readProto(w http.ResponseWriter, r *http.Request, message proto.Message) bool {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = proto.Unmarshal(body, message)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeProto(w http.ResponseWriter, message proto.Message) {
+	body, _ := proto.Marshal(message)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(body)
+}
+
+// Export implements the gRPC metrics service, the HTTP handler reuses it
+func (c *fakeCollector) Export(_ context.Context, request *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, resourceMetrics := range request.ResourceMetrics {
+		c.serviceNames = append(c.serviceNames, serviceName(resourceMetrics.Resource.GetAttributes()))
+		for _, scope := range resourceMetrics.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				c.metricNames = append(c.metricNames, m.Name)
+			}
+		}
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// traceService adapts the collector to the gRPC trace service, whose Export clashes with the metrics one
+type traceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	collector *fakeCollector
+}
+
+func (s traceService) Export(_ context.Context, request *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	s.collector.exportTraces(request)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *fakeCollector) exportTraces(request *coltracepb.ExportTraceServiceRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, resourceSpans := range request.ResourceSpans {
+		c.serviceNames = append(c.serviceNames, serviceName(resourceSpans.Resource.GetAttributes()))
+		for _, scope := range resourceSpans.ScopeSpans {
+			for _, span := range scope.Spans {
+				c.spanNames = append(c.spanNames, span.Name)
+			}
+		}
+	}
+}
+
+func serviceName(attributes []*commonpb.KeyValue) string {
+	for _, attribute := range attributes {
+		if attribute.Key == "service.name" {
+			return attribute.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+// received returns copies of the metric names, span names and service names seen so far
+func (c *fakeCollector) received() (metricNames, spanNames, serviceNames []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.metricNames...), append([]string(nil), c.spanNames...), append([]string(nil), c.serviceNames...)
+}
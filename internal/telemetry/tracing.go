@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ValidateTracesExporter returns an error if exporter is not one of the supported trace exporters
+func ValidateTracesExporter(exporter string) error {
+	switch exporter {
+	case ExporterOTLP, ExporterNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown traces exporter %q, expected %s or %s", exporter, ExporterOTLP, ExporterNone)
+	}
+}
+
+// NewTracerProvider creates a tracer provider that exports spans with the given exporter.
+// With the none exporter spans are still created, so trace context keeps propagating, but never exported.
+func NewTracerProvider(ctx context.Context, exporter string, res *sdkresource.Resource) (*sdktrace.TracerProvider, func(), error) {
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	switch exporter {
+	case ExporterOTLP:
+		otlpExporter, err := newOTLPTraceExporter(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create otlp trace exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(otlpExporter))
+
+	case ExporterNone:
+
+	default:
+		return nil, nil, ValidateTracesExporter(exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	shutdown := func() {
+		// Same as the meter provider, flush the pending spans even though the context is cancelled
+		err := provider.Shutdown(context.WithoutCancel(ctx))
+		if err != nil {
+			slog.Error("failed to shutdown tracer provider", "err", err)
+		}
+	}
+
+	return provider, shutdown, nil
+}
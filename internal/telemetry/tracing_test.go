@@ -0,0 +1,23 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	provider, shutdown, err := NewTracerProvider(context.Background(), ExporterNone, resource.Empty())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider == nil || shutdown == nil {
+		t.Fatalf("provider or shutdown was nil")
+	}
+	shutdown()
+
+	if _, _, err := NewTracerProvider(context.Background(), ExporterPrometheus, resource.Empty()); err == nil {
+		t.Fatalf("expected error for an exporter that cannot export traces")
+	}
+}